@@ -0,0 +1,231 @@
+package ossfs
+
+import (
+	"container/list"
+	"crypto/md5"
+	"fmt"
+	"io"
+	"os"
+	"sync"
+
+	"github.com/spf13/afero"
+)
+
+// blockKey identifies a cached block, the ETag makes blocks of an outdated
+// object never be read again.
+type blockKey struct {
+	name  string
+	etag  string
+	index int64
+}
+
+type cacheBlock struct {
+	key  blockKey
+	size int64
+}
+
+// blockCache caches fixed-size blocks of objects in a backing afero.Fs, the
+// least recently used blocks are evicted to keep the total size within
+// maxSize.
+type blockCache struct {
+	store     afero.Fs
+	blockSize int64
+	maxSize   int64
+
+	mu     sync.Mutex
+	size   int64
+	lru    *list.List // of *cacheBlock, the most recently used first.
+	blocks map[blockKey]*list.Element
+}
+
+func newBlockCache(store afero.Fs, blockSize, maxSize int64) *blockCache {
+	return &blockCache{
+		store:     store,
+		blockSize: blockSize,
+		maxSize:   maxSize,
+		lru:       list.New(),
+		blocks:    make(map[blockKey]*list.Element),
+	}
+}
+
+// WithBlockCache enables caching of read data, objects are read in blocks of
+// blockSize bytes which are cached in store, e.g. afero.NewMemMapFs() or an
+// afero.NewBasePathFs of a local directory, until the total size exceeds
+// maxSize and the least recently used blocks are evicted. A nil store caches
+// blocks in memory.
+//
+// Blocks are keyed by object name and ETag, and the blocks of an object are
+// dropped when it's written, renamed or removed through fs.
+//
+// A non-positive blockSize or maxSize disables the cache.
+func (fs *Fs) WithBlockCache(store afero.Fs, blockSize, maxSize int64) *Fs {
+	if blockSize <= 0 || maxSize <= 0 {
+		fs.cache = nil
+		return fs
+	}
+	if store == nil {
+		store = afero.NewMemMapFs()
+	}
+	fs.cache = newBlockCache(store, blockSize, maxSize)
+	return fs
+}
+
+// path returns the path of the block file in the store.
+func (c *blockCache) path(key blockKey) string {
+	sum := md5.Sum([]byte(key.name + "\x00" + key.etag))
+	return fmt.Sprintf("%x-%d", sum, key.index)
+}
+
+// get returns the data of the block, it returns false on a cache miss.
+func (c *blockCache) get(key blockKey) ([]byte, bool) {
+	c.mu.Lock()
+	e, found := c.blocks[key]
+	if found {
+		c.lru.MoveToFront(e)
+	}
+	c.mu.Unlock()
+	if !found {
+		return nil, false
+	}
+
+	data, err := afero.ReadFile(c.store, c.path(key))
+	if err != nil {
+		// The block file is gone, e.g. it was just evicted.
+		c.mu.Lock()
+		c.remove(key)
+		c.mu.Unlock()
+		return nil, false
+	}
+	return data, true
+}
+
+// put caches the data of the block, it's not cached if it cannot be saved in
+// the store.
+func (c *blockCache) put(key blockKey, data []byte) {
+	size := int64(len(data))
+	if size > c.maxSize {
+		return
+	}
+	if err := afero.WriteFile(c.store, c.path(key), data, 0o644); err != nil {
+		return
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if e, found := c.blocks[key]; found {
+		c.size -= e.Value.(*cacheBlock).size
+		e.Value.(*cacheBlock).size = size
+		c.lru.MoveToFront(e)
+	} else {
+		c.blocks[key] = c.lru.PushFront(&cacheBlock{key: key, size: size})
+	}
+	c.size += size
+	for c.size > c.maxSize {
+		c.remove(c.lru.Back().Value.(*cacheBlock).key)
+	}
+}
+
+// remove drops the block, the caller must hold the lock.
+func (c *blockCache) remove(key blockKey) {
+	e, found := c.blocks[key]
+	if !found {
+		return
+	}
+	c.size -= e.Value.(*cacheBlock).size
+	c.lru.Remove(e)
+	delete(c.blocks, key)
+	_ = c.store.Remove(c.path(key))
+}
+
+// invalidate drops all blocks of the object.
+func (c *blockCache) invalidate(name string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for key := range c.blocks {
+		if key.name == name {
+			c.remove(key)
+		}
+	}
+}
+
+// invalidateCache drops the cached blocks and metadata of the object if the
+// caches are enabled.
+func (fs *Fs) invalidateCache(name string) {
+	if fs.cache != nil {
+		fs.cache.invalidate(name)
+	}
+	if fs.metaCache != nil {
+		fs.metaCache.invalidate(name)
+	}
+}
+
+// cachedMeta returns the FileInfo of the file used to read through the block
+// cache, it's fetched once and kept until the file is written.
+func (f *File) cachedMeta() (os.FileInfo, error) {
+	f.metaMu.Lock()
+	defer f.metaMu.Unlock()
+	if f.meta == nil {
+		fi, err := f.fs.Stat(f.name)
+		if err != nil {
+			return nil, err
+		}
+		f.meta = fi
+	}
+	return f.meta, nil
+}
+
+// resetMeta drops the FileInfo kept by cachedMeta.
+func (f *File) resetMeta() {
+	f.metaMu.Lock()
+	f.meta = nil
+	f.metaMu.Unlock()
+}
+
+// readCached reads len(p) bytes at off through the block cache, missing blocks
+// are read from the object and cached.
+func (f *File) readCached(p []byte, off int64) (int, error) {
+	fi, err := f.cachedMeta()
+	if err != nil {
+		return 0, err
+	}
+	size := fi.Size()
+	if off >= size {
+		return 0, io.EOF
+	}
+	var eof error
+	if off+int64(len(p)) > size {
+		p = p[:size-off]
+		eof = io.EOF
+	}
+
+	etag := ""
+	if v, ok := fi.(interface{ ETag() string }); ok {
+		etag = v.ETag()
+	}
+	c := f.fs.cache
+	n := 0
+	for n < len(p) {
+		pos := off + int64(n)
+		key := blockKey{name: f.name, etag: etag, index: pos / c.blockSize}
+		data, found := c.get(key)
+		if !found {
+			start := key.index * c.blockSize
+			data = make([]byte, min(c.blockSize, size-start))
+			k, err := f.readRange(data, start)
+			if err != nil && err != io.EOF {
+				return n, err
+			}
+			data = data[:k]
+			if etag != "" {
+				c.put(key, data)
+			}
+		}
+		i := pos - key.index*c.blockSize
+		if i >= int64(len(data)) {
+			// The object was truncated since its FileInfo was fetched.
+			return n, io.EOF
+		}
+		n += copy(p[n:], data[i:])
+	}
+	return n, eof
+}
@@ -0,0 +1,150 @@
+package ossfs
+
+import (
+	"context"
+	"io"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/spf13/afero"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBlockCache(t *testing.T) {
+	store := afero.NewMemMapFs()
+	c := newBlockCache(store, 4, 10)
+	a0 := blockKey{name: "a", etag: "1", index: 0}
+	a1 := blockKey{name: "a", etag: "1", index: 1}
+	b0 := blockKey{name: "b", etag: "1", index: 0}
+
+	t.Run("put and get", func(t *testing.T) {
+		c.put(a0, []byte("0123"))
+		data, found := c.get(a0)
+		assert.True(t, found)
+		assert.Equal(t, "0123", string(data))
+
+		_, found = c.get(blockKey{name: "a", etag: "2", index: 0})
+		assert.False(t, found)
+	})
+
+	t.Run("evict least recently used", func(t *testing.T) {
+		c.put(a1, []byte("4567"))
+		_, _ = c.get(a0)
+		c.put(b0, []byte("abcd"))
+
+		assert.Equal(t, int64(8), c.size)
+		_, found := c.get(a1)
+		assert.False(t, found)
+		_, found = c.get(a0)
+		assert.True(t, found)
+		exists, _ := afero.Exists(store, c.path(a1))
+		assert.False(t, exists)
+	})
+
+	t.Run("block larger than budget is not cached", func(t *testing.T) {
+		c.put(blockKey{name: "c"}, make([]byte, 11))
+		_, found := c.get(blockKey{name: "c"})
+		assert.False(t, found)
+	})
+
+	t.Run("missing block file is a miss", func(t *testing.T) {
+		assert.NoError(t, store.Remove(c.path(b0)))
+		_, found := c.get(b0)
+		assert.False(t, found)
+		assert.Equal(t, int64(4), c.size)
+	})
+
+	t.Run("invalidate object", func(t *testing.T) {
+		c.put(b0, []byte("abcd"))
+		c.invalidate("a")
+		_, found := c.get(a0)
+		assert.False(t, found)
+		_, found = c.get(b0)
+		assert.True(t, found)
+		assert.Equal(t, int64(4), c.size)
+	})
+}
+
+func TestFsWithBlockCache(t *testing.T) {
+	m := NewMemObjectManager()
+	_, _ = m.PutObject(context.TODO(), "test-bucket", "a.txt", strings.NewReader("0123456789"))
+
+	t.Run("enable cache", func(t *testing.T) {
+		fs := NewOssFsWithManager(m, "test-bucket")
+		assert.Same(t, fs, fs.WithBlockCache(nil, 4, 1024))
+		assert.NotNil(t, fs.cache)
+	})
+
+	t.Run("non-positive sizes disable cache", func(t *testing.T) {
+		for _, sizes := range [][2]int64{{0, 1024}, {-1, 1024}, {4, 0}, {4, -1}} {
+			fs := NewOssFsWithManager(m, "test-bucket").WithBlockCache(nil, 4, 1024)
+			fs.WithBlockCache(nil, sizes[0], sizes[1])
+			assert.Nil(t, fs.cache)
+			b, err := afero.ReadFile(fs, "a.txt")
+			assert.NoError(t, err)
+			assert.Equal(t, "0123456789", string(b))
+		}
+	})
+}
+
+func TestFileReadThroughBlockCache(t *testing.T) {
+	stores := map[string]afero.Fs{
+		"memory":    nil,
+		"directory": afero.NewBasePathFs(afero.NewOsFs(), t.TempDir()),
+	}
+
+	for name, store := range stores {
+		t.Run(name, func(t *testing.T) {
+			m := &countingManager{MemObjectManager: NewMemObjectManager()}
+			ctx := context.TODO()
+			_, _ = m.PutObject(ctx, "test-bucket", "hot.txt", strings.NewReader("0123456789"))
+			fs := NewOssFsWithManager(m, "test-bucket").WithBlockCache(store, 4, 1024)
+
+			read := func() string {
+				f, err := fs.OpenFile("hot.txt", os.O_RDONLY, 0o644)
+				assert.NoError(t, err)
+				b, err := io.ReadAll(f)
+				assert.NoError(t, err)
+				assert.NoError(t, f.Close())
+				return string(b)
+			}
+
+			assert.Equal(t, "0123456789", read())
+			assert.Equal(t, int32(3), m.requests.Load())
+			assert.Equal(t, "0123456789", read())
+			assert.Equal(t, int32(3), m.requests.Load())
+
+			// ReadAt shares the cached blocks.
+			f, _ := fs.OpenFile("hot.txt", os.O_RDONLY, 0o644)
+			p := make([]byte, 4)
+			n, err := f.ReadAt(p, 7)
+			assert.ErrorIs(t, err, io.EOF)
+			assert.Equal(t, "789", string(p[:n]))
+			assert.Equal(t, int32(3), m.requests.Load())
+			assert.NoError(t, f.Close())
+
+			// a changed object has another ETag.
+			_, _ = m.PutObject(ctx, "test-bucket", "hot.txt", strings.NewReader("abcdef"))
+			assert.Equal(t, "abcdef", read())
+			assert.Equal(t, int32(5), m.requests.Load())
+
+			// the blocks are dropped when the object is written.
+			f, _ = fs.OpenFile("hot.txt", os.O_RDWR, 0o644)
+			_, err = f.WriteAt([]byte("XY"), 0)
+			assert.NoError(t, err)
+			assert.NoError(t, f.Close())
+			assert.Empty(t, fs.cache.blocks)
+			assert.Equal(t, "XYcdef", read())
+
+			// and when it's renamed or removed.
+			assert.NoError(t, fs.Rename("hot.txt", "cold.txt"))
+			assert.Empty(t, fs.cache.blocks)
+			f, _ = fs.OpenFile("cold.txt", os.O_RDONLY, 0o644)
+			_, _ = io.ReadAll(f)
+			assert.NotEmpty(t, fs.cache.blocks)
+			assert.NoError(t, fs.Remove("cold.txt"))
+			assert.Empty(t, fs.cache.blocks)
+		})
+	}
+}
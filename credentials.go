@@ -0,0 +1,318 @@
+package ossfs
+
+import (
+	"bufio"
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha1"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/aliyun/alibabacloud-oss-go-sdk-v2/oss"
+	"github.com/aliyun/alibabacloud-oss-go-sdk-v2/oss/credentials"
+)
+
+const (
+	defaultSTSEndpoint        = "https://sts.aliyuncs.com"
+	defaultSTSDurationSeconds = 3600
+	defaultCredentialsProfile = "default"
+)
+
+// NewOssFsWithCredentials creates a new ossfs.Fs object whose requests are
+// signed by the credentials of provider, e.g. EnvCredentials, STSCredentials,
+// FileCredentials, EcsRamRoleCredentials or any other provider of the SDK.
+func NewOssFsWithCredentials(provider credentials.CredentialsProvider, region, bucket string, opts ...Option) *Fs {
+	ossCfg := oss.LoadDefaultConfig().
+		WithCredentialsProvider(provider).
+		WithRegion(region)
+
+	var fsOpts []FsOption
+	for _, opt := range opts {
+		switch o := opt.(type) {
+		case OSSOptionFunc:
+			o(ossCfg)
+		case FsOption:
+			fsOpts = append(fsOpts, o)
+		}
+	}
+
+	fs := NewOssFsFromClient(oss.NewClient(ossCfg), bucket, fsOpts...)
+	fs.ossCfg = ossCfg
+	return fs
+}
+
+// EnvCredentials returns a provider of the credentials in the environment
+// variables OSS_ACCESS_KEY_ID, OSS_ACCESS_KEY_SECRET and OSS_SESSION_TOKEN,
+// or else ALIBABA_CLOUD_ACCESS_KEY_ID, ALIBABA_CLOUD_ACCESS_KEY_SECRET and
+// ALIBABA_CLOUD_SECURITY_TOKEN. The variables are read on every request, so
+// rotated credentials are picked up.
+func EnvCredentials() credentials.CredentialsProvider {
+	return credentials.CredentialsProviderFunc(func(ctx context.Context) (credentials.Credentials, error) {
+		for _, names := range [][3]string{
+			{"OSS_ACCESS_KEY_ID", "OSS_ACCESS_KEY_SECRET", "OSS_SESSION_TOKEN"},
+			{"ALIBABA_CLOUD_ACCESS_KEY_ID", "ALIBABA_CLOUD_ACCESS_KEY_SECRET", "ALIBABA_CLOUD_SECURITY_TOKEN"},
+		} {
+			id, secret := os.Getenv(names[0]), os.Getenv(names[1])
+			if id != "" && secret != "" {
+				return credentials.Credentials{
+					AccessKeyID:     id,
+					AccessKeySecret: secret,
+					SecurityToken:   os.Getenv(names[2]),
+				}, nil
+			}
+		}
+		return credentials.Credentials{}, errors.New("ossfs: no credentials in environment variables")
+	})
+}
+
+// EcsRamRoleCredentials returns a provider of the credentials of the RAM role
+// attached to the ECS instance, which are refreshed before they expire. The
+// role is queried from the instance metadata if it's empty.
+func EcsRamRoleCredentials(role string) credentials.CredentialsProvider {
+	return credentials.NewEcsRoleCredentialsProvider(credentials.EcsRamRole(role))
+}
+
+// STSOptions configures STSCredentials.
+type STSOptions struct {
+	// The endpoint of STS, it's https://sts.aliyuncs.com by default.
+	Endpoint string
+
+	// The validity of the security tokens, 3600 seconds by default.
+	DurationSeconds int
+
+	// The policy further restricting the permissions of the role, and the
+	// external ID of the role, both optional.
+	Policy     string
+	ExternalId string
+
+	// The client of the requests to STS, http.DefaultClient by default.
+	HTTPClient *http.Client
+}
+
+// STSCredentials returns a provider of the security tokens of STS by assuming
+// the role roleArn with the credentials of base, the tokens are refreshed
+// before they expire.
+func STSCredentials(base credentials.CredentialsProvider, roleArn, sessionName string, optFns ...func(*STSOptions)) credentials.CredentialsProvider {
+	opts := STSOptions{
+		Endpoint:        defaultSTSEndpoint,
+		DurationSeconds: defaultSTSDurationSeconds,
+		HTTPClient:      http.DefaultClient,
+	}
+	for _, fn := range optFns {
+		fn(&opts)
+	}
+	return credentials.NewCredentialsFetcherProvider(credentials.CredentialsFetcherFunc(func(ctx context.Context) (credentials.Credentials, error) {
+		return assumeRole(ctx, base, roleArn, sessionName, opts)
+	}))
+}
+
+// assumeRole requests the AssumeRole API of STS.
+func assumeRole(ctx context.Context, base credentials.CredentialsProvider, roleArn, sessionName string, opts STSOptions) (credentials.Credentials, error) {
+	cred, err := base.GetCredentials(ctx)
+	if err != nil {
+		return credentials.Credentials{}, err
+	}
+
+	nonce := make([]byte, 16)
+	_, _ = rand.Read(nonce)
+	params := map[string]string{
+		"Action":           "AssumeRole",
+		"Version":          "2015-04-01",
+		"Format":           "JSON",
+		"AccessKeyId":      cred.AccessKeyID,
+		"SignatureMethod":  "HMAC-SHA1",
+		"SignatureVersion": "1.0",
+		"SignatureNonce":   hex.EncodeToString(nonce),
+		"Timestamp":        time.Now().UTC().Format("2006-01-02T15:04:05Z"),
+		"RoleArn":          roleArn,
+		"RoleSessionName":  sessionName,
+		"DurationSeconds":  strconv.Itoa(opts.DurationSeconds),
+	}
+	if cred.SecurityToken != "" {
+		params["SecurityToken"] = cred.SecurityToken
+	}
+	if opts.Policy != "" {
+		params["Policy"] = opts.Policy
+	}
+	if opts.ExternalId != "" {
+		params["ExternalId"] = opts.ExternalId
+	}
+	query := canonicalizeRPCQuery(params)
+	query += "&Signature=" + rpcPercentEncode(signRPC(http.MethodGet, query, cred.AccessKeySecret))
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, strings.TrimSuffix(opts.Endpoint, "/")+"/?"+query, nil)
+	if err != nil {
+		return credentials.Credentials{}, err
+	}
+	resp, err := opts.HTTPClient.Do(req)
+	if err != nil {
+		return credentials.Credentials{}, err
+	}
+	defer resp.Body.Close()
+
+	var result struct {
+		RequestId   string
+		Code        string
+		Message     string
+		Credentials struct {
+			AccessKeyId     string
+			AccessKeySecret string
+			SecurityToken   string
+			Expiration      time.Time
+		}
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return credentials.Credentials{}, fmt.Errorf("ossfs: invalid AssumeRole response: %w", err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return credentials.Credentials{}, fmt.Errorf("ossfs: AssumeRole failed: %s: %s, request id: %s", result.Code, result.Message, result.RequestId)
+	}
+	c := result.Credentials
+	return credentials.Credentials{
+		AccessKeyID:     c.AccessKeyId,
+		AccessKeySecret: c.AccessKeySecret,
+		SecurityToken:   c.SecurityToken,
+		Expires:         &c.Expiration,
+	}, nil
+}
+
+// canonicalizeRPCQuery joins the params sorted by name, as signed by the RPC
+// signature of Alibaba Cloud APIs.
+func canonicalizeRPCQuery(params map[string]string) string {
+	keys := make([]string, 0, len(params))
+	for k := range params {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	pairs := make([]string, len(keys))
+	for i, k := range keys {
+		pairs[i] = rpcPercentEncode(k) + "=" + rpcPercentEncode(params[k])
+	}
+	return strings.Join(pairs, "&")
+}
+
+// signRPC returns the RPC signature of the canonicalized query.
+func signRPC(method, query, secret string) string {
+	stringToSign := method + "&" + rpcPercentEncode("/") + "&" + rpcPercentEncode(query)
+	mac := hmac.New(sha1.New, []byte(secret+"&"))
+	mac.Write([]byte(stringToSign))
+	return base64.StdEncoding.EncodeToString(mac.Sum(nil))
+}
+
+func rpcPercentEncode(s string) string {
+	s = url.QueryEscape(s)
+	s = strings.ReplaceAll(s, "+", "%20")
+	s = strings.ReplaceAll(s, "*", "%2A")
+	return strings.ReplaceAll(s, "%7E", "~")
+}
+
+// FileCredentials returns a provider of the credentials of profile in the
+// credentials file of Alibaba Cloud, an INI file such as:
+//
+//	[default]
+//	type = access_key
+//	access_key_id = foo
+//	access_key_secret = bar
+//
+// The types access_key, sts (with security_token), ram_role_arn (with
+// role_arn and role_session_name, see STSCredentials, which is configured by
+// stsOpts) and ecs_ram_role (with role_name) are supported.
+//
+// If path is empty, the file is ALIBABA_CLOUD_CREDENTIALS_FILE or
+// ~/.alibabacloud/credentials. If profile is empty, it's ALIBABA_CLOUD_PROFILE
+// or default.
+func FileCredentials(path, profile string, stsOpts ...func(*STSOptions)) (credentials.CredentialsProvider, error) {
+	if path == "" {
+		path = os.Getenv("ALIBABA_CLOUD_CREDENTIALS_FILE")
+	}
+	if path == "" {
+		home, err := os.UserHomeDir()
+		if err != nil {
+			return nil, err
+		}
+		path = filepath.Join(home, ".alibabacloud", "credentials")
+	}
+	if profile == "" {
+		profile = os.Getenv("ALIBABA_CLOUD_PROFILE")
+	}
+	if profile == "" {
+		profile = defaultCredentialsProfile
+	}
+
+	profiles, err := readCredentialsFile(path)
+	if err != nil {
+		return nil, err
+	}
+	p, found := profiles[profile]
+	if !found {
+		return nil, fmt.Errorf("ossfs: profile %q not found in %s", profile, path)
+	}
+
+	typ := p["type"]
+	if typ == "" {
+		typ = "access_key"
+	}
+	switch typ {
+	case "access_key", "sts":
+		if p["access_key_id"] == "" || p["access_key_secret"] == "" {
+			return nil, fmt.Errorf("ossfs: profile %q has no access key", profile)
+		}
+		return credentials.NewStaticCredentialsProvider(p["access_key_id"], p["access_key_secret"], p["security_token"]), nil
+	case "ram_role_arn":
+		if p["access_key_id"] == "" || p["access_key_secret"] == "" || p["role_arn"] == "" {
+			return nil, fmt.Errorf("ossfs: profile %q has no access key or role_arn", profile)
+		}
+		base := credentials.NewStaticCredentialsProvider(p["access_key_id"], p["access_key_secret"])
+		sessionName := p["role_session_name"]
+		if sessionName == "" {
+			sessionName = "ossfs"
+		}
+		return STSCredentials(base, p["role_arn"], sessionName, stsOpts...), nil
+	case "ecs_ram_role":
+		return EcsRamRoleCredentials(p["role_name"]), nil
+	}
+	return nil, fmt.Errorf("ossfs: profile %q has unsupported type %q", profile, typ)
+}
+
+// readCredentialsFile reads the key-value pairs of the profiles in the INI
+// file.
+func readCredentialsFile(path string) (map[string]map[string]string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	profiles := make(map[string]map[string]string)
+	var section map[string]string
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		switch {
+		case line == "" || line[0] == '#' || line[0] == ';':
+		case line[0] == '[' && line[len(line)-1] == ']':
+			name := strings.TrimSpace(line[1 : len(line)-1])
+			section = make(map[string]string)
+			profiles[name] = section
+		default:
+			k, v, ok := strings.Cut(line, "=")
+			if !ok || section == nil {
+				continue
+			}
+			section[strings.TrimSpace(k)] = strings.Trim(strings.TrimSpace(v), `"`)
+		}
+	}
+	return profiles, scanner.Err()
+}
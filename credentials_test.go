@@ -0,0 +1,255 @@
+package ossfs
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/aliyun/alibabacloud-oss-go-sdk-v2/oss/credentials"
+	"github.com/spf13/afero"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/messikiller/afero-oss/osstest"
+)
+
+// stubSTS is a local STS endpoint serving AssumeRole, it verifies the
+// signatures by the secret of the base credentials.
+type stubSTS struct {
+	*httptest.Server
+	secret  string
+	expires time.Duration
+	calls   atomic.Int32
+}
+
+func newStubSTS(t *testing.T, secret string, expires time.Duration) *stubSTS {
+	s := &stubSTS{secret: secret, expires: expires}
+	s.Server = httptest.NewServer(http.HandlerFunc(s.serve))
+	t.Cleanup(s.Close)
+	return s
+}
+
+func (s *stubSTS) serve(w http.ResponseWriter, r *http.Request) {
+	n := s.calls.Add(1)
+	q := r.URL.Query()
+	params := make(map[string]string)
+	for k := range q {
+		if k != "Signature" {
+			params[k] = q.Get(k)
+		}
+	}
+	if q.Get("Action") != "AssumeRole" || q.Get("Signature") != signRPC(r.Method, canonicalizeRPCQuery(params), s.secret) {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(map[string]string{
+			"RequestId": "stub-request", "Code": "SignatureDoesNotMatch", "Message": "signature mismatch",
+		})
+		return
+	}
+	json.NewEncoder(w).Encode(map[string]any{
+		"RequestId": "stub-request",
+		"Credentials": map[string]any{
+			"AccessKeyId":     "STS.id",
+			"AccessKeySecret": "sts-secret",
+			"SecurityToken":   fmt.Sprintf("token-%d-%s", n, q.Get("RoleSessionName")),
+			"Expiration":      time.Now().Add(s.expires).UTC().Format(time.RFC3339),
+		},
+	})
+}
+
+func (s *stubSTS) endpoint(o *STSOptions) {
+	o.Endpoint = s.URL
+}
+
+// TestSignRPC checks the RPC signature by the example of the signature
+// documentation of Alibaba Cloud, which signs DescribeRegions of ECS.
+func TestSignRPC(t *testing.T) {
+	params := map[string]string{
+		"Timestamp":        "2016-02-23T12:46:24Z",
+		"Format":           "XML",
+		"AccessKeyId":      "testid",
+		"Action":           "DescribeRegions",
+		"SignatureMethod":  "HMAC-SHA1",
+		"SignatureNonce":   "3ee8c1b8-83d3-44af-a94f-4e0ad82fd6cf",
+		"Version":          "2014-05-26",
+		"SignatureVersion": "1.0",
+	}
+	query := canonicalizeRPCQuery(params)
+	assert.Equal(t, "AccessKeyId=testid&Action=DescribeRegions&Format=XML&SignatureMethod=HMAC-SHA1"+
+		"&SignatureNonce=3ee8c1b8-83d3-44af-a94f-4e0ad82fd6cf&SignatureVersion=1.0"+
+		"&Timestamp=2016-02-23T12%3A46%3A24Z&Version=2014-05-26", query)
+	assert.Equal(t, "OLeaidS1JvxuMvnyHOwuJ+uX5qY=", signRPC(http.MethodGet, query, "testsecret"))
+
+	for s, expected := range map[string]string{
+		"a b":        "a%20b",
+		"a*b":        "a%2Ab",
+		"a~b":        "a~b",
+		"a+b/c=d&e":  "a%2Bb%2Fc%3Dd%26e",
+		"-_.":        "-_.",
+		"中":          "%E4%B8%AD",
+		"role:1/arn": "role%3A1%2Farn",
+	} {
+		assert.Equal(t, expected, rpcPercentEncode(s), s)
+	}
+}
+
+func TestSTSCredentials(t *testing.T) {
+	base := credentials.NewStaticCredentialsProvider("base-id", "base-secret")
+
+	t.Run("assume role", func(t *testing.T) {
+		sts := newStubSTS(t, "base-secret", time.Hour)
+		p := STSCredentials(base, "acs:ram::1:role/test", "session", sts.endpoint)
+		cred, err := p.GetCredentials(context.TODO())
+		assert.NoError(t, err)
+		assert.Equal(t, "STS.id", cred.AccessKeyID)
+		assert.Equal(t, "sts-secret", cred.AccessKeySecret)
+		assert.Equal(t, "token-1-session", cred.SecurityToken)
+		assert.NotNil(t, cred.Expires)
+
+		_, err = p.GetCredentials(context.TODO())
+		assert.NoError(t, err)
+		assert.Equal(t, int32(1), sts.calls.Load())
+	})
+
+	t.Run("refresh expired token", func(t *testing.T) {
+		sts := newStubSTS(t, "base-secret", -time.Second)
+		p := STSCredentials(base, "acs:ram::1:role/test", "session", sts.endpoint)
+		_, err := p.GetCredentials(context.TODO())
+		assert.NoError(t, err)
+		cred, err := p.GetCredentials(context.TODO())
+		assert.NoError(t, err)
+		assert.Equal(t, "token-2-session", cred.SecurityToken)
+		assert.Equal(t, int32(2), sts.calls.Load())
+	})
+
+	t.Run("assume role fails", func(t *testing.T) {
+		sts := newStubSTS(t, "other-secret", time.Hour)
+		p := STSCredentials(base, "acs:ram::1:role/test", "session", sts.endpoint)
+		_, err := p.GetCredentials(context.TODO())
+		assert.ErrorContains(t, err, "SignatureDoesNotMatch")
+		assert.ErrorContains(t, err, "stub-request")
+	})
+
+	t.Run("fs with sts credentials", func(t *testing.T) {
+		sts := newStubSTS(t, "base-secret", time.Hour)
+		srv := osstest.NewServer("test-bucket")
+		defer srv.Close()
+		fs := NewOssFsWithCredentials(STSCredentials(base, "acs:ram::1:role/test", "session", sts.endpoint),
+			"cn-hangzhou", "test-bucket", OSSWithEndpoint(srv.URL), OSSWithUsePathStyle())
+		assert.NoError(t, afero.WriteFile(fs, "a.txt", []byte("hello"), 0o644))
+		b, err := afero.ReadFile(fs, "a.txt")
+		assert.NoError(t, err)
+		assert.Equal(t, "hello", string(b))
+		assert.Equal(t, int32(1), sts.calls.Load())
+	})
+}
+
+func TestEnvCredentials(t *testing.T) {
+	for _, name := range []string{
+		"OSS_ACCESS_KEY_ID", "OSS_ACCESS_KEY_SECRET", "OSS_SESSION_TOKEN",
+		"ALIBABA_CLOUD_ACCESS_KEY_ID", "ALIBABA_CLOUD_ACCESS_KEY_SECRET", "ALIBABA_CLOUD_SECURITY_TOKEN",
+	} {
+		t.Setenv(name, "")
+	}
+	p := EnvCredentials()
+
+	t.Run("no credentials", func(t *testing.T) {
+		_, err := p.GetCredentials(context.TODO())
+		assert.Error(t, err)
+	})
+
+	t.Run("alibaba cloud variables", func(t *testing.T) {
+		t.Setenv("ALIBABA_CLOUD_ACCESS_KEY_ID", "cloud-id")
+		t.Setenv("ALIBABA_CLOUD_ACCESS_KEY_SECRET", "cloud-secret")
+		t.Setenv("ALIBABA_CLOUD_SECURITY_TOKEN", "cloud-token")
+		cred, err := p.GetCredentials(context.TODO())
+		assert.NoError(t, err)
+		assert.Equal(t, credentials.Credentials{AccessKeyID: "cloud-id", AccessKeySecret: "cloud-secret", SecurityToken: "cloud-token"}, cred)
+
+		t.Run("oss variables take precedence", func(t *testing.T) {
+			t.Setenv("OSS_ACCESS_KEY_ID", "oss-id")
+			t.Setenv("OSS_ACCESS_KEY_SECRET", "oss-secret")
+			cred, err := p.GetCredentials(context.TODO())
+			assert.NoError(t, err)
+			assert.Equal(t, credentials.Credentials{AccessKeyID: "oss-id", AccessKeySecret: "oss-secret"}, cred)
+		})
+	})
+}
+
+func TestFileCredentials(t *testing.T) {
+	sts := newStubSTS(t, "role-secret", time.Hour)
+	path := filepath.Join(t.TempDir(), "credentials")
+	assert.NoError(t, os.WriteFile(path, []byte(`
+# comment
+[default]
+type = access_key
+access_key_id = default-id
+access_key_secret = default-secret
+
+[token]
+type = sts
+access_key_id = sts-id
+access_key_secret = sts-secret
+security_token = "sts-token"
+
+[role]
+type = ram_role_arn
+access_key_id = role-id
+access_key_secret = role-secret
+role_arn = acs:ram::1:role/test
+role_session_name = file-session
+
+[unknown]
+type = oidc_role_arn
+`), 0o600))
+
+	get := func(t *testing.T, path, profile string) (credentials.Credentials, error) {
+		p, err := FileCredentials(path, profile, sts.endpoint)
+		if err != nil {
+			return credentials.Credentials{}, err
+		}
+		return p.GetCredentials(context.TODO())
+	}
+
+	t.Run("access key", func(t *testing.T) {
+		cred, err := get(t, path, "")
+		assert.NoError(t, err)
+		assert.Equal(t, "default-id", cred.AccessKeyID)
+		assert.Equal(t, "default-secret", cred.AccessKeySecret)
+	})
+
+	t.Run("security token", func(t *testing.T) {
+		cred, err := get(t, path, "token")
+		assert.NoError(t, err)
+		assert.Equal(t, "sts-token", cred.SecurityToken)
+	})
+
+	t.Run("ram role arn", func(t *testing.T) {
+		cred, err := get(t, path, "role")
+		assert.NoError(t, err)
+		assert.Equal(t, "STS.id", cred.AccessKeyID)
+		assert.Equal(t, "token-1-file-session", cred.SecurityToken)
+	})
+
+	t.Run("file and profile from environment variables", func(t *testing.T) {
+		t.Setenv("ALIBABA_CLOUD_CREDENTIALS_FILE", path)
+		t.Setenv("ALIBABA_CLOUD_PROFILE", "token")
+		cred, err := get(t, "", "")
+		assert.NoError(t, err)
+		assert.Equal(t, "sts-id", cred.AccessKeyID)
+	})
+
+	t.Run("invalid profiles", func(t *testing.T) {
+		_, err := get(t, path, "missing")
+		assert.ErrorContains(t, err, `profile "missing" not found`)
+		_, err = get(t, path, "unknown")
+		assert.ErrorContains(t, err, `unsupported type "oidc_role_arn"`)
+		_, err = get(t, filepath.Join(t.TempDir(), "missing"), "")
+		assert.ErrorIs(t, err, os.ErrNotExist)
+	})
+}
@@ -0,0 +1,90 @@
+package ossfs
+
+import (
+	iofs "io/fs"
+	"os"
+	"syscall"
+)
+
+// WithoutDirectoryMarkers never writes the zero-byte directory markers, e.g.
+// for buckets shared with tools which don't expect them. Directories are then
+// only the prefixes of objects: Mkdir and MkdirAll write nothing but fail if
+// a file has the name, a directory exists as long as there are objects under
+// it, and Remove fails on a directory which isn't empty. Directory markers
+// written by others are still recognized. It's ignored on buckets with
+// hierarchical namespace, where directories are real.
+func (fs *Fs) WithoutDirectoryMarkers() *Fs {
+	fs.noDirMarkers = true
+	return fs
+}
+
+// statDir returns the FileInfo of the directory name, which is named either
+// by a directory marker, or implicitly by the objects under it, e.g. the
+// prefixes of objects uploaded by other tools without markers. The implicit
+// directory is found by listing at most one entry under it.
+func (fs *Fs) statDir(name string) (os.FileInfo, error) {
+	if name == "" {
+		return fs.newDirInfo(fs.ensureAsDir(name)), nil
+	}
+	dir := fs.ensureAsDir(name)
+	if dir != name {
+		fi, err := fs.stat(dir)
+		if !isNotFound(err) {
+			return fi, err
+		}
+	}
+	fis, next, err := fs.listObjects(dir, "", 1)
+	if err != nil {
+		return nil, err
+	}
+	if len(fis) == 0 && next == "" {
+		return nil, iofs.ErrNotExist
+	}
+	return fs.newDirInfo(dir), nil
+}
+
+// dirPrefix returns the prefix of the objects under the directory name, which
+// is empty for the root.
+func (fs *Fs) dirPrefix(name string) string {
+	if name == "" {
+		return ""
+	}
+	return fs.ensureAsDir(name)
+}
+
+// mkdirPrefix makes the directory without writing a directory marker, it only
+// checks that no file has the name of the directory.
+func (fs *Fs) mkdirPrefix(name string) error {
+	name = fs.trimDir(name)
+	if name == "" {
+		return nil
+	}
+	existed, err := fs.objectExists(name)
+	if err != nil {
+		return err
+	}
+	if existed {
+		return syscall.ENOTDIR
+	}
+	return nil
+}
+
+// checkEmptyDir returns syscall.ENOTEMPTY if name is a directory having
+// objects under it, i.e. it ends with the separator or no object has the
+// name.
+func (fs *Fs) checkEmptyDir(name string) error {
+	if name != "" && !fs.isDir(name) {
+		existed, err := fs.objectExists(name)
+		if err != nil || existed {
+			return err
+		}
+	}
+	fis, next, err := fs.listObjects(fs.dirPrefix(name), "", 1)
+	if err != nil {
+		return err
+	}
+	if len(fis) > 0 || next != "" {
+		return syscall.ENOTEMPTY
+	}
+	return nil
+}
@@ -0,0 +1,188 @@
+package ossfs
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"syscall"
+	"testing"
+
+	"github.com/spf13/afero"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/messikiller/afero-oss/osstest"
+)
+
+func TestFsImplicitDirectories(t *testing.T) {
+	srv := osstest.NewServer("test-bucket")
+	defer srv.Close()
+
+	for name, fs := range map[string]*Fs{
+		"mem":      NewOssFsWithManager(NewMemObjectManager(), "test-bucket"),
+		"emulated": NewOssFs("ak", "sk", "cn-hangzhou", "test-bucket", OSSWithEndpoint(srv.URL), OSSWithUsePathStyle()),
+	} {
+		t.Run(name, func(t *testing.T) {
+			// Objects uploaded without directory markers.
+			for _, key := range []string{"a/b/c.txt", "a/d.txt", "e.txt"} {
+				_, err := fs.manager.PutObject(context.TODO(), "test-bucket", key, strings.NewReader(key))
+				assert.NoError(t, err)
+			}
+
+			t.Run("stat implicit directory", func(t *testing.T) {
+				for _, name := range []string{"a", "a/", "/a/b", "a/b/"} {
+					fi, err := fs.Stat(name)
+					assert.NoError(t, err)
+					assert.True(t, fi.IsDir(), name)
+					assert.True(t, fi.Mode().IsDir(), name)
+				}
+				isDir, err := afero.IsDir(fs, "a/b")
+				assert.NoError(t, err)
+				assert.True(t, isDir)
+			})
+
+			t.Run("stat root", func(t *testing.T) {
+				fi, err := fs.Stat("/")
+				assert.NoError(t, err)
+				assert.True(t, fi.IsDir())
+			})
+
+			t.Run("missing names do not exist", func(t *testing.T) {
+				for _, name := range []string{"x", "a/x/", "a/d.txt/"} {
+					_, err := fs.Stat(name)
+					assert.ErrorIs(t, err, os.ErrNotExist, name)
+				}
+				_, err := fs.OpenFile("a/x", os.O_RDONLY, 0o644)
+				assert.ErrorIs(t, err, os.ErrNotExist)
+			})
+
+			t.Run("open implicit directory", func(t *testing.T) {
+				f, err := fs.Open("a")
+				assert.NoError(t, err)
+				assert.Equal(t, "a/", f.Name())
+				names, err := f.Readdirnames(-1)
+				assert.NoError(t, err)
+				assert.Equal(t, []string{"b", "d.txt"}, names)
+				assert.NoError(t, f.Close())
+
+				f, err = fs.Open("/")
+				assert.NoError(t, err)
+				names, err = f.Readdirnames(-1)
+				assert.NoError(t, err)
+				assert.Equal(t, []string{"a", "e.txt"}, names)
+				assert.NoError(t, f.Close())
+			})
+
+			t.Run("walk", func(t *testing.T) {
+				var walked []string
+				err := afero.Walk(fs, "/", func(path string, info os.FileInfo, err error) error {
+					if err != nil {
+						return err
+					}
+					if info.IsDir() {
+						path += "/"
+					}
+					walked = append(walked, filepath.ToSlash(path))
+					return nil
+				})
+				assert.NoError(t, err)
+				assert.Equal(t, []string{"//", "/a/", "/a/b/", "/a/b/c.txt", "/a/d.txt", "/e.txt"}, walked)
+			})
+		})
+	}
+
+	t.Run("custom separator", func(t *testing.T) {
+		m := NewMemObjectManager()
+		_, err := m.PutObject(context.TODO(), "test-bucket", "dir:a.txt", strings.NewReader("a"))
+		assert.NoError(t, err)
+		fs := NewOssFsWithManager(m, "test-bucket", WithSeparator(":"))
+
+		fi, err := fs.Stat("dir")
+		assert.NoError(t, err)
+		assert.Equal(t, "dir:", fi.Name())
+		assert.True(t, fi.IsDir())
+		assert.True(t, fi.Mode().IsDir())
+		isDir, err := afero.IsDir(fs, "dir")
+		assert.NoError(t, err)
+		assert.True(t, isDir)
+
+		fi, err = fs.Stat("/")
+		assert.NoError(t, err)
+		assert.True(t, fi.IsDir())
+	})
+}
+
+func TestFsWithoutDirectoryMarkers(t *testing.T) {
+	m := NewMemObjectManager()
+	fs := NewOssFsWithManager(m, "test-bucket", WithoutDirectoryMarkers())
+	markers := func(t *testing.T) []string {
+		var keys []string
+		for fi, err := range m.IterObjects(context.TODO(), "test-bucket", "", true) {
+			assert.NoError(t, err)
+			if strings.HasSuffix(fi.Name(), "/") {
+				keys = append(keys, fi.Name())
+			}
+		}
+		return keys
+	}
+
+	t.Run("mkdir writes no markers", func(t *testing.T) {
+		assert.NoError(t, fs.Mkdir("a", 0o755))
+		assert.NoError(t, fs.MkdirAll("a/b/c/", 0o755))
+		assert.Empty(t, markers(t))
+		_, err := fs.Stat("a")
+		assert.ErrorIs(t, err, os.ErrNotExist)
+	})
+
+	t.Run("mkdir still validates", func(t *testing.T) {
+		assert.NoError(t, afero.WriteFile(fs, "a/b/c.txt", []byte("c"), 0o644))
+		assert.ErrorIs(t, fs.Mkdir("a/b/c.txt", 0o755), syscall.ENOTDIR)
+		assert.ErrorIs(t, fs.MkdirAll("/a/b/c.txt/", 0o755), syscall.ENOTDIR)
+		assert.ErrorIs(t, fs.Mkdir("", 0o755), os.ErrInvalid)
+		assert.NoError(t, fs.MkdirAll("a/b", 0o755))
+		assert.NoError(t, fs.MkdirAll("/", 0o755))
+	})
+
+	t.Run("open directories writes no markers", func(t *testing.T) {
+		for _, name := range []string{"a", "a/b/"} {
+			f, err := fs.OpenFile(name, os.O_RDWR|os.O_CREATE|os.O_TRUNC, 0o644)
+			assert.NoError(t, err)
+			assert.NoError(t, f.Close())
+		}
+		f, err := fs.Create("d/")
+		assert.NoError(t, err)
+		assert.NoError(t, f.Close())
+		assert.Empty(t, markers(t))
+	})
+
+	t.Run("readdir lists prefixes", func(t *testing.T) {
+		_, _ = m.PutObject(context.TODO(), "test-bucket", "a/e/", strings.NewReader(""))
+		fis, err := afero.ReadDir(fs, "a")
+		assert.NoError(t, err)
+		if assert.Len(t, fis, 2) {
+			assert.Equal(t, "b", fis[0].Name())
+			assert.Equal(t, "e", fis[1].Name())
+			assert.True(t, fis[0].IsDir())
+			assert.True(t, fis[1].IsDir())
+		}
+	})
+
+	t.Run("remove non-empty directory fails", func(t *testing.T) {
+		assert.ErrorIs(t, fs.Remove("a/b"), syscall.ENOTEMPTY)
+		assert.ErrorIs(t, fs.Remove("a/b/"), syscall.ENOTEMPTY)
+		assert.NoError(t, fs.Remove("a/e/"))
+		assert.NoError(t, fs.Remove("a/b/c.txt"))
+		_, err := fs.Stat("a")
+		assert.ErrorIs(t, err, os.ErrNotExist)
+	})
+
+	t.Run("remove all", func(t *testing.T) {
+		assert.NoError(t, afero.WriteFile(fs, "f/g/h.txt", []byte("h"), 0o644))
+		assert.NoError(t, afero.WriteFile(fs, "f/i.txt", []byte("i"), 0o644))
+		assert.NoError(t, fs.RemoveAll("f"))
+		existed, err := afero.DirExists(fs, "f")
+		assert.NoError(t, err)
+		assert.False(t, existed)
+		assert.Empty(t, markers(t))
+	})
+}
@@ -0,0 +1,99 @@
+package ossfs
+
+import (
+	"errors"
+	"io"
+	iofs "io/fs"
+	"log/slog"
+	"net/http"
+
+	"github.com/aliyun/alibabacloud-oss-go-sdk-v2/oss"
+)
+
+// serviceError is an error of OSS service mapped to a standard fs error, the
+// original error is still reachable by errors.As, e.g. for its request ID.
+type serviceError struct {
+	err  error
+	kind error
+}
+
+func (e *serviceError) Error() string {
+	return e.err.Error()
+}
+
+func (e *serviceError) Unwrap() error {
+	return e.err
+}
+
+func (e *serviceError) Is(target error) bool {
+	return target == e.kind
+}
+
+// isNotFound returns whether err is caused by a missing object.
+func isNotFound(err error) bool {
+	var serr *oss.ServiceError
+	return errors.Is(err, iofs.ErrNotExist) ||
+		errors.As(err, &serr) && isNoSuchKey(serr)
+}
+
+// isNoSuchKey returns whether serr reports a missing object, rather than e.g.
+// a missing bucket. The 404 response of HEAD may have no error code, as it
+// has no body.
+func isNoSuchKey(serr *oss.ServiceError) bool {
+	return serr.Code == "NoSuchKey" ||
+		serr.StatusCode == http.StatusNotFound && serr.Code == ""
+}
+
+// mapError maps the error of OSS service to the standard fs errors.
+//
+// A missing object is reported by fs.ErrNotExist itself: os.IsNotExist, and
+// the afero helpers built on it, e.g. afero.Exists, compare the Err of
+// *fs.PathError with fs.ErrNotExist by ==, so it can't wrap the service
+// error. Its request ID is logged by Fs.wrapError instead. Other errors are
+// wrapped as *serviceError, so they match fs.ErrPermission or fs.ErrExist by
+// errors.Is, and the others, e.g. NoSuchBucket, are kept as they are. Joined
+// errors, e.g. of RemoveAll, are kept as they are too.
+func mapError(err error) error {
+	if _, joined := err.(interface{ Unwrap() []error }); joined {
+		return err
+	}
+	var serr *oss.ServiceError
+	if !errors.As(err, &serr) {
+		return err
+	}
+	switch {
+	case isNoSuchKey(serr):
+		return iofs.ErrNotExist
+	case serr.StatusCode == http.StatusForbidden || serr.Code == "AccessDenied":
+		return &serviceError{err: err, kind: iofs.ErrPermission}
+	case serr.StatusCode == http.StatusPreconditionFailed || serr.Code == "FileAlreadyExists":
+		return &serviceError{err: err, kind: iofs.ErrExist}
+	}
+	return err
+}
+
+// pathError wraps err in *fs.PathError with the operation and the path, and
+// maps the errors of OSS service by mapError. Nil, io.EOF and errors already
+// wrapped are returned as they are.
+func pathError(op, path string, err error) error {
+	if err == nil || err == io.EOF {
+		return err
+	}
+	if _, ok := err.(*iofs.PathError); ok {
+		return err
+	}
+	return &iofs.PathError{Op: op, Path: path, Err: mapError(err)}
+}
+
+// wrapError replaces *errp by pathError, it's deferred by the methods of Fs
+// and File. The request ID of a missing object, which is dropped by mapError,
+// is logged at debug level, see WithLogger.
+func (fs *Fs) wrapError(op, path string, errp *error) {
+	var serr *oss.ServiceError
+	if fs.logger != nil && errors.As(*errp, &serr) && isNoSuchKey(serr) {
+		fs.logger.Debug("ossfs: object not found", slog.String("op", op),
+			slog.String("bucket", fs.bucketName), slog.String("name", path),
+			slog.String("request_id", serr.RequestID))
+	}
+	*errp = pathError(op, path, *errp)
+}
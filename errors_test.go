@@ -0,0 +1,172 @@
+package ossfs
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"log/slog"
+	"net/http"
+	"os"
+	"strings"
+	"syscall"
+	"testing"
+
+	"github.com/aliyun/alibabacloud-oss-go-sdk-v2/oss"
+	"github.com/spf13/afero"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/messikiller/afero-oss/osstest"
+)
+
+func TestMapError(t *testing.T) {
+	serviceErr := func(status int, code string) error {
+		return &oss.ServiceError{StatusCode: status, Code: code, RequestID: "request-id"}
+	}
+
+	tests := []struct {
+		name string
+		err  error
+		kind error
+	}{
+		{"access denied", serviceErr(http.StatusForbidden, "AccessDenied"), fs.ErrPermission},
+		{"precondition failed", serviceErr(http.StatusPreconditionFailed, "PreconditionFailed"), fs.ErrExist},
+		{"file already exists", serviceErr(http.StatusConflict, "FileAlreadyExists"), fs.ErrExist},
+		{"wrapped service error", fmt.Errorf("operation error: %w", serviceErr(http.StatusForbidden, "AccessDenied")), fs.ErrPermission},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := pathError("stat", "a.txt", tt.err)
+			var pe *fs.PathError
+			assert.ErrorAs(t, err, &pe)
+			assert.Equal(t, "stat", pe.Op)
+			assert.Equal(t, "a.txt", pe.Path)
+			assert.ErrorIs(t, err, tt.kind)
+			assert.False(t, os.IsNotExist(err))
+			var serr *oss.ServiceError
+			assert.ErrorAs(t, err, &serr)
+			assert.Equal(t, "request-id", serr.RequestID)
+		})
+	}
+
+	t.Run("missing object", func(t *testing.T) {
+		for _, orig := range []error{
+			serviceErr(http.StatusNotFound, "NoSuchKey"),
+			serviceErr(http.StatusNotFound, ""),
+		} {
+			err := pathError("stat", "a.txt", orig)
+			var pe *fs.PathError
+			assert.ErrorAs(t, err, &pe)
+			assert.Equal(t, fs.ErrNotExist, pe.Err)
+			assert.True(t, os.IsNotExist(err))
+		}
+	})
+
+	t.Run("missing bucket is not mapped", func(t *testing.T) {
+		orig := serviceErr(http.StatusNotFound, "NoSuchBucket")
+		err := pathError("stat", "a.txt", orig)
+		assert.False(t, os.IsNotExist(err))
+		assert.NotErrorIs(t, err, fs.ErrNotExist)
+		assert.Equal(t, orig, errors.Unwrap(err))
+	})
+
+	t.Run("other errors are kept", func(t *testing.T) {
+		orig := serviceErr(http.StatusInternalServerError, "InternalError")
+		err := pathError("stat", "a.txt", orig)
+		assert.Equal(t, orig, errors.Unwrap(err))
+	})
+
+	t.Run("joined errors are kept", func(t *testing.T) {
+		orig := errors.Join(serviceErr(http.StatusNotFound, "NoSuchKey"), syscall.EIO)
+		err := pathError("removeall", "dir", orig)
+		assert.Equal(t, orig, errors.Unwrap(err))
+	})
+
+	t.Run("nil and io.EOF are not wrapped", func(t *testing.T) {
+		assert.NoError(t, pathError("read", "a.txt", nil))
+		assert.Equal(t, io.EOF, pathError("read", "a.txt", io.EOF))
+	})
+
+	t.Run("path errors are not wrapped again", func(t *testing.T) {
+		err := pathError("stat", "a.txt", syscall.EPERM)
+		assert.Same(t, err, pathError("open", "b.txt", err))
+	})
+}
+
+func TestFsErrors(t *testing.T) {
+	m := NewMemObjectManager()
+	_, _ = m.PutObject(context.TODO(), "test-bucket", "dir/", strings.NewReader(""))
+	ossFs := NewOssFsWithManager(m, "test-bucket")
+
+	t.Run("stat missing file", func(t *testing.T) {
+		_, err := ossFs.Stat("missing.txt")
+		assert.ErrorIs(t, err, fs.ErrNotExist)
+		assert.True(t, os.IsNotExist(err))
+		assert.EqualError(t, err, "stat missing.txt: file does not exist")
+
+		existed, err := afero.Exists(ossFs, "missing.txt")
+		assert.NoError(t, err)
+		assert.False(t, existed)
+	})
+
+	t.Run("open missing file", func(t *testing.T) {
+		_, err := ossFs.OpenFile("missing.txt", os.O_RDONLY, 0)
+		assert.True(t, os.IsNotExist(err))
+	})
+
+	t.Run("read directory", func(t *testing.T) {
+		f, err := ossFs.Open("dir/")
+		assert.NoError(t, err)
+		_, err = f.Read(make([]byte, 1))
+		var pe *fs.PathError
+		assert.ErrorAs(t, err, &pe)
+		assert.Equal(t, "read", pe.Op)
+		assert.Equal(t, "dir/", pe.Path)
+		assert.ErrorIs(t, err, syscall.EPERM)
+		assert.NoError(t, f.Close())
+	})
+
+	t.Run("service errors of emulated bucket", func(t *testing.T) {
+		srv := osstest.NewServer("test-bucket")
+		defer srv.Close()
+		ossFs := NewOssFs("ak", "sk", "cn-hangzhou", "test-bucket",
+			OSSWithEndpoint(srv.URL), OSSWithUsePathStyle())
+
+		existed, err := afero.Exists(ossFs, "missing.txt")
+		assert.NoError(t, err)
+		assert.False(t, existed)
+
+		missing := NewOssFs("ak", "sk", "cn-hangzhou", "missing-bucket",
+			OSSWithEndpoint(srv.URL), OSSWithUsePathStyle())
+		_, err = missing.Stat("a.txt")
+		assert.False(t, os.IsNotExist(err))
+		var serr *oss.ServiceError
+		if assert.ErrorAs(t, err, &serr) {
+			assert.Equal(t, "NoSuchBucket", serr.Code)
+		}
+		_, err = afero.Exists(missing, "a.txt")
+		assert.Error(t, err)
+	})
+
+	t.Run("request ID of missing object is logged", func(t *testing.T) {
+		var buf bytes.Buffer
+		logger := slog.New(slog.NewTextHandler(&buf, &slog.HandlerOptions{Level: slog.LevelDebug}))
+		srv := osstest.NewServer("test-bucket")
+		defer srv.Close()
+		ossFs := NewOssFsWithOptions("ak", "sk", "cn-hangzhou", "test-bucket",
+			OSSWithEndpoint(srv.URL), OSSWithUsePathStyle(), WithLogger(logger))
+		assert.NoError(t, afero.WriteFile(ossFs, "gone.txt", []byte("gone"), 0o644))
+		f, err := ossFs.OpenFile("gone.txt", os.O_RDONLY, 0)
+		assert.NoError(t, err)
+		assert.NoError(t, ossFs.Remove("gone.txt"))
+
+		_, err = f.Read(make([]byte, 1))
+		assert.True(t, os.IsNotExist(err))
+		assert.Contains(t, buf.String(), "ossfs: object not found")
+		assert.Contains(t, buf.String(), "op=read")
+		assert.Regexp(t, `request_id=\w+`, buf.String())
+		assert.NoError(t, f.Close())
+	})
+}
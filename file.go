@@ -6,6 +6,7 @@ import (
 	"os"
 	"sync"
 	"syscall"
+	"time"
 
 	"github.com/spf13/afero"
 )
@@ -17,15 +18,27 @@ type File struct {
 	offset   int64
 	isDir    bool
 
-	// Whether the file is written.
-	dirty bool
-
 	// Whether the file is closed.
 	closed bool
 
-	// Whether the file is preloaded downto local.
-	preloaded   bool
-	preloadedFd afero.File
+	// The state shared with the other handles of the file, which holds the
+	// preloaded copy, it's acquired on the first write.
+	shared *sharedFile
+
+	// The streaming writer of a write-only file, see Fs.WithMultipartUpload.
+	writer *multipartWriter
+
+	// The body being read by sequential reads.
+	stream *readStream
+
+	// The continuation token of the next Readdir, and whether all entries of
+	// the directory are read.
+	dirToken string
+	dirEOF   bool
+
+	// The FileInfo used to read through the block cache.
+	meta   os.FileInfo
+	metaMu sync.Mutex
 
 	mu sync.Mutex
 }
@@ -33,57 +46,29 @@ type File struct {
 // NewOssFile creates a new File instance, the name of file will be normalized.
 func NewOssFile(name string, flag int, fs *Fs) (*File, error) {
 	return &File{
-		name:        fs.normFileName(name),
-		fs:          fs,
-		openFlag:    flag,
-		offset:      0,
-		dirty:       false,
-		closed:      false,
-		isDir:       fs.isDir(fs.normFileName(name)),
-		preloaded:   false,
-		preloadedFd: nil,
+		name:     fs.normFileName(name),
+		fs:       fs,
+		openFlag: flag,
+		offset:   0,
+		closed:   false,
+		isDir:    fs.isDir(fs.normFileName(name)),
 	}, nil
 }
 
-// preload will preload the file to specific preload-filesystem.
-func (f *File) preload() error {
-	pfs := f.fs.preloadFs
-	if _, err := pfs.Stat(f.name); err == nil {
-		if e := pfs.Remove(f.name); e != nil {
-			return e
-		}
-	}
-	pfd, err := f.fs.preloadFs.Create(f.name)
-	if err != nil {
-		return err
-	}
-
-	r, clean, e := f.fs.manager.GetObject(f.fs.ctx, f.fs.bucketName, f.name)
-	if e != nil {
-		return e
-	}
-	defer clean()
-
-	if _, err := io.Copy(pfd, r); err != nil {
-		return err
-	}
-
-	if _, err := pfd.Seek(f.offset, io.SeekStart); err != nil {
-		return err
-	}
-
-	f.preloadedFd = pfd
-	f.preloaded = true
-	return nil
-}
-
 // getFileInfo returns the FileInfo of file.
 func (f *File) getFileInfo() (os.FileInfo, error) {
-	if f.dirty {
-		if f.preloadedFd == nil {
-			return nil, syscall.EACCES
+	if f.writer != nil {
+		return NewFileInfo(f.name, f.writer.size, time.Now()), nil
+	}
+	if s := f.shared; s != nil {
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		if s.dirty {
+			if s.preloadedFd == nil {
+				return nil, syscall.EACCES
+			}
+			return s.preloadedFd.Stat()
 		}
-		return f.preloadedFd.Stat()
 	}
 	return f.fs.Stat(f.name)
 }
@@ -112,42 +97,85 @@ func (f *File) isWriteable() bool {
 	return masked == os.O_WRONLY || masked == os.O_RDWR
 }
 
+// isWriteOnly returns whether the file is write-only by openFlag of the file instance.
+func (f *File) isWriteOnly() bool {
+	return f.isWriteable() && f.openFlag&0x3 == os.O_WRONLY
+}
+
 // isAppendOnly returns whether the file is append-only by openFlag of the file instance.
 func (f *File) isAppendOnly() bool {
 	return f.isWriteable() && f.openFlag&os.O_APPEND != 0
 }
 
 // Read reads up to len(p) bytes from the File, it implements interface: io.Reader.
-func (f *File) Read(p []byte) (int, error) {
+// Sequential reads share a single streaming body, which is reopened only if the
+// offset was changed, e.g. by Seek.
+func (f *File) Read(p []byte) (_ int, err error) {
+	defer f.fs.wrapError("read", f.name, &err)
 	if !f.isReadable() || f.isDir {
 		return 0, syscall.EPERM
 	}
-	n, err := f.ReadAt(p, f.offset)
-	if err != nil {
-		return n, err
-	}
 	f.mu.Lock()
 	defer f.mu.Unlock()
+
+	if n, ok, err := f.readPreloaded(p, f.offset); ok {
+		f.offset += int64(n)
+		if n > 0 && err == io.EOF {
+			err = nil
+		}
+		return n, err
+	}
+
+	if f.fs.cache != nil {
+		n, err := f.readCached(p, f.offset)
+		f.offset += int64(n)
+		if n > 0 && err == io.EOF {
+			err = nil
+		}
+		return n, err
+	}
+
+	if f.stream != nil && f.stream.offset != f.offset {
+		f.closeStream()
+	}
+	if f.stream == nil {
+		s, err := f.openStream(f.offset)
+		if err != nil {
+			return 0, err
+		}
+		f.stream = s
+	}
+	n, err := f.stream.Read(p)
 	f.offset += int64(n)
 	return n, err
 }
 
 // ReadAt reads len(p) bytes from the File starting at byte offset off into p.
-// It implements interface: io.ReaderAt.
-func (f *File) ReadAt(p []byte, off int64) (int, error) {
+// It implements interface: io.ReaderAt, so p is filled unless an error
+// happens, io.EOF is returned if the file ends before that.
+func (f *File) ReadAt(p []byte, off int64) (_ int, err error) {
+	defer f.fs.wrapError("read", f.name, &err)
 	if !f.isReadable() || f.isDir {
 		return 0, syscall.EPERM
 	}
-	reader, cleanUp, err := f.fs.manager.GetObjectPart(f.fs.ctx, f.fs.bucketName, f.name, off, off+int64(len(p)))
-	if err != nil {
-		return 0, err
+	if off < 0 {
+		return 0, syscall.EINVAL
+	}
+	if n, ok, err := f.readPreloaded(p, off); ok {
+		return n, err
+	}
+	if f.fs.cache != nil {
+		return f.readCached(p, off)
+	}
+	if f.fs.parallelReadSize > 0 && int64(len(p)) > f.fs.parallelReadSize {
+		return f.readRangeParallel(p, off)
 	}
-	defer cleanUp()
-	return reader.Read(p)
+	return f.readRange(p, off)
 }
 
 // Seek sets the offset for the next Read or Write on file to offset,
-func (f *File) Seek(offset int64, whence int) (int64, error) {
+func (f *File) Seek(offset int64, whence int) (_ int64, err error) {
+	defer f.fs.wrapError("seek", f.name, &err)
 	if (!f.isReadable() && !f.isWriteable()) || f.isDir {
 		return 0, syscall.EPERM
 	}
@@ -174,12 +202,17 @@ func (f *File) Seek(offset int64, whence int) (int64, error) {
 	if newOffset < 0 || newOffset > max {
 		return 0, afero.ErrOutOfRange
 	}
+	if f.writer != nil && newOffset != f.offset {
+		// A streamed file can only be written sequentially.
+		return 0, syscall.ESPIPE
+	}
 	f.offset = newOffset
 	return f.offset, nil
 }
 
 // Write writes len(p) bytes to the File. It implements interface: io.Writer.
-func (f *File) Write(p []byte) (int, error) {
+func (f *File) Write(p []byte) (_ int, err error) {
+	defer f.fs.wrapError("write", f.name, &err)
 	if !f.isWriteable() {
 		return 0, syscall.EPERM
 	}
@@ -187,6 +220,12 @@ func (f *File) Write(p []byte) (int, error) {
 	f.mu.Lock()
 	defer f.mu.Unlock()
 
+	if f.writer != nil {
+		n, err := f.writer.Write(p)
+		f.offset += int64(n)
+		return n, err
+	}
+
 	if f.isAppendOnly() {
 		fi, err := f.getFileInfo()
 		if err != nil {
@@ -203,29 +242,38 @@ func (f *File) Write(p []byte) (int, error) {
 	return n, e
 }
 
-// doWriteAt write len(p) bytes at the offset of the File. It will preload file into preload-filesystem.
+// doWriteAt write len(p) bytes at the offset of the File. It will preload file
+// into preload-filesystem, the preloaded copy is shared by the handles of the
+// file.
 func (f *File) doWriteAt(p []byte, off int64) (int, error) {
 	if f.isDir {
 		return 0, syscall.EPERM
 	}
 
-	if !f.preloaded {
-		if err := f.preload(); err != nil {
-			return 0, err
-		}
+	if f.shared == nil {
+		f.shared = f.fs.acquireShared(f.name)
 	}
-
-	n, e := f.preloadedFd.WriteAt(p, off)
-	f.dirty = true
-	if f.fs.autoSync {
-		f.Sync()
+	s := f.shared
+	s.mu.Lock()
+	if err := s.preload(f.fs); err != nil {
+		s.mu.Unlock()
+		return 0, err
 	}
+	n, e := s.preloadedFd.WriteAt(p, off)
+	s.dirty = true
+	s.pending = true
+	s.mu.Unlock()
+
+	f.resetMeta()
+	f.closeStream()
+	f.afterWrite(n)
 	return n, e
 }
 
 // WriteAt writes len(p) bytes to the File starting at byte offset off.
 // It implements interface: io.WriterAt.
-func (f *File) WriteAt(p []byte, off int64) (int, error) {
+func (f *File) WriteAt(p []byte, off int64) (_ int, err error) {
+	defer f.fs.wrapError("write", f.name, &err)
 	if !f.isWriteable() || f.isAppendOnly() {
 		return 0, syscall.EPERM
 	}
@@ -234,53 +282,121 @@ func (f *File) WriteAt(p []byte, off int64) (int, error) {
 	}
 	f.mu.Lock()
 	defer f.mu.Unlock()
+	if f.writer != nil {
+		if off != f.writer.size {
+			return 0, syscall.ESPIPE
+		}
+		n, err := f.writer.Write(p)
+		f.offset += int64(n)
+		return n, err
+	}
 	return f.doWriteAt(p, off)
 }
 
-// Close will close the file and remove it from opened files map.
-// It implements interface: io.Closer.
-func (f *File) Close() error {
+// Close will close the file, and release the preloaded copy shared with the
+// other handles of the file. It implements interface: io.Closer.
+func (f *File) Close() (err error) {
+	defer f.fs.wrapError("close", f.name, &err)
 	f.mu.Lock()
 	defer f.mu.Unlock()
 
-	err := f.Sync()
+	if f.writer != nil {
+		// The handle is closed even if the upload failed and was aborted.
+		err := f.writer.Close()
+		f.writer = nil
+		f.fs.invalidateCache(f.name)
+		f.fs.unregister(f)
+		f.closed = true
+		return err
+	}
+
+	err = f.syncLocked()
 	if err != nil {
 		return err
 	}
-	delete(f.fs.openedFiles, f.name)
-	if f.preloaded {
-		err := f.fs.preloadFs.Remove(f.name)
-		if err != nil {
-			return err
-		}
-		err = f.preloadedFd.Close()
+	f.closeStream()
+	if f.shared != nil {
+		err = f.fs.releaseShared(f.shared)
+		f.shared = nil
 		if err != nil {
 			return err
 		}
-		f.preloadedFd = nil
-		f.preloaded = false
 	}
-	f.dirty = false
+	f.fs.unregister(f)
 	f.closed = true
 	return nil
 }
 
+// syncWriter waits for the uploading parts of a streamed file, see Fs.Flush.
+func (f *File) syncWriter() error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.writer == nil {
+		return nil
+	}
+	return pathError("sync", f.name, f.writer.Sync())
+}
+
+// abandon closes the file failed to be closed, the preloaded copy is released
+// without being synced, see Fs.Close.
+func (f *File) abandon() {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.closeStream()
+	if f.shared != nil {
+		f.fs.logError("ossfs: release failed", f.name, f.fs.releaseShared(f.shared))
+		f.shared = nil
+	}
+	f.fs.unregister(f)
+	f.closed = true
+}
+
 func (f *File) Name() string {
 	return f.name
 }
 
-// Readdir read count files from the directory.
-func (f *File) Readdir(count int) ([]os.FileInfo, error) {
+// Readdir read count files from the directory, subdirectories are included as
+// entries with ModeDir, and entries are named by their base names.
+//
+// Successive calls continue from the entries returned before as os.File does.
+// If count > 0, at most count entries are returned, and io.EOF is returned at
+// the end of the directory. If count <= 0, all the remaining entries are
+// returned with a nil error.
+func (f *File) Readdir(count int) (_ []os.FileInfo, err error) {
+	defer f.fs.wrapError("readdirent", f.name, &err)
 	if !f.isReadable() || !f.isDir {
 		return nil, syscall.EPERM
 	}
 
-	fis, err := f.fs.manager.ListObjects(f.fs.ctx, f.fs.bucketName, f.fs.ensureAsDir(f.name), count)
-	return fis, err
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if f.dirEOF {
+		if count > 0 {
+			return nil, io.EOF
+		}
+		return []os.FileInfo{}, nil
+	}
+
+	fis, next, err := f.fs.listObjects(f.fs.dirPrefix(f.name), f.dirToken, count)
+	if err != nil {
+		return nil, err
+	}
+	f.dirToken = next
+	f.dirEOF = next == ""
+
+	if count > 0 && len(fis) == 0 {
+		return nil, io.EOF
+	}
+	for i, fi := range fis {
+		fis[i] = newDirEntry(fi, f.fs.baseName(fi.Name()))
+	}
+	return fis, nil
 }
 
 // Readdirnames read n file names form the directory.
-func (f *File) Readdirnames(n int) ([]string, error) {
+func (f *File) Readdirnames(n int) (_ []string, err error) {
+	defer f.fs.wrapError("readdirent", f.name, &err)
 	if !f.isReadable() || !f.isDir {
 		return nil, syscall.EPERM
 	}
@@ -299,29 +415,40 @@ func (f *File) Readdirnames(n int) ([]string, error) {
 	return fNames, nil
 }
 
-func (f *File) Stat() (os.FileInfo, error) {
+func (f *File) Stat() (_ os.FileInfo, err error) {
+	defer f.fs.wrapError("stat", f.name, &err)
 	return f.getFileInfo()
 }
 
-// Sync will sync the preloaded file into cloud storage.
+// Sync will sync the preloaded file into cloud storage, a streamed file
+// waits for its uploading parts. A preloaded file larger than the part size of
+// resumable uploads is uploaded from its checkpoint, see
+// Fs.WithResumableUpload.
 func (f *File) Sync() error {
-	if f.preloaded && f.preloadedFd != nil {
-		off, _ := f.preloadedFd.Seek(0, io.SeekCurrent)
-		f.preloadedFd.Seek(0, io.SeekStart)
-		if _, err := f.fs.manager.PutObject(f.fs.ctx, f.fs.bucketName, f.name, f.preloadedFd); err != nil {
-			return err
-		}
-		f.preloadedFd.Seek(off, io.SeekStart)
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.syncLocked()
+}
+
+// syncLocked is Sync for the callers holding f.mu.
+func (f *File) syncLocked() (err error) {
+	defer f.fs.wrapError("sync", f.name, &err)
+	if f.writer != nil {
+		return f.writer.Sync()
 	}
-	return nil
+	if f.shared == nil {
+		return nil
+	}
+	return f.fs.syncShared(f.shared)
 }
 
-func (f *File) Truncate(size int64) error {
-	if !f.isWriteable() || f.isDir {
+func (f *File) Truncate(size int64) (err error) {
+	defer f.fs.wrapError("truncate", f.name, &err)
+	if !f.isWriteable() || f.isDir || f.writer != nil {
 		return syscall.EPERM
 	}
 	p := make([]byte, size)
-	_, err := f.Write(p)
+	_, err = f.Write(p)
 	return err
 }
 
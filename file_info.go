@@ -1,6 +1,7 @@
 package ossfs
 
 import (
+	"os"
 	"time"
 
 	"github.com/messikiller/afero-oss/internal/utils"
@@ -15,3 +16,41 @@ func NewFileInfo(name string, size int64, updatedAt time.Time) *FileInfo {
 		OssObjectMeta: utils.NewOssObjectMeta(name, size, updatedAt),
 	}
 }
+
+// newDirInfo returns the FileInfo of the directory name, which ends with the
+// separator of the Fs.
+func (fs *Fs) newDirInfo(name string) *FileInfo {
+	sep := fs.separator
+	if sep == "" {
+		sep = "/"
+	}
+	return &FileInfo{
+		OssObjectMeta: utils.NewOssDirMeta(name, sep),
+	}
+}
+
+// dirEntry is the FileInfo of a directory entry, which is named by its base
+// name as os.File.Readdir does.
+type dirEntry struct {
+	os.FileInfo
+	name string
+}
+
+func newDirEntry(fi os.FileInfo, name string) *dirEntry {
+	return &dirEntry{
+		FileInfo: fi,
+		name:     name,
+	}
+}
+
+func (e *dirEntry) Name() string {
+	return e.name
+}
+
+// ETag returns the entity tag of the wrapped FileInfo, it's empty if unknown.
+func (e *dirEntry) ETag() string {
+	if fi, ok := e.FileInfo.(interface{ ETag() string }); ok {
+		return fi.ETag()
+	}
+	return ""
+}
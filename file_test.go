@@ -1,6 +1,7 @@
 package ossfs
 
 import (
+	"context"
 	"fmt"
 	"io"
 	"os"
@@ -39,11 +40,9 @@ func TestNewOssFile(t *testing.T) {
 		assert.Equal(t, "testfile", file.name)
 		assert.Equal(t, os.O_RDONLY, file.openFlag)
 		assert.Equal(t, fs, file.fs)
-		assert.False(t, file.dirty)
 		assert.False(t, file.closed)
 		assert.False(t, file.isDir)
-		assert.False(t, file.preloaded)
-		assert.Nil(t, file.preloadedFd)
+		assert.Nil(t, file.shared)
 	})
 
 	t.Run("create new file with write flag", func(t *testing.T) {
@@ -53,11 +52,9 @@ func TestNewOssFile(t *testing.T) {
 		assert.Equal(t, "testfile", file.name)
 		assert.Equal(t, os.O_WRONLY, file.openFlag)
 		assert.Equal(t, fs, file.fs)
-		assert.False(t, file.dirty)
 		assert.False(t, file.closed)
 		assert.False(t, file.isDir)
-		assert.False(t, file.preloaded)
-		assert.Nil(t, file.preloadedFd)
+		assert.Nil(t, file.shared)
 	})
 
 	t.Run("create new directory", func(t *testing.T) {
@@ -67,11 +64,9 @@ func TestNewOssFile(t *testing.T) {
 		assert.Equal(t, "testdir/", file.name)
 		assert.Equal(t, os.O_RDONLY, file.openFlag)
 		assert.Equal(t, fs, file.fs)
-		assert.False(t, file.dirty)
 		assert.False(t, file.closed)
 		assert.True(t, file.isDir)
-		assert.False(t, file.preloaded)
-		assert.Nil(t, file.preloadedFd)
+		assert.Nil(t, file.shared)
 	})
 
 	t.Run("normalize file name", func(t *testing.T) {
@@ -190,7 +185,7 @@ func TesFileRead(t *testing.T) {
 		_, e := f.Read(p)
 
 		assert.Error(t, e)
-		assert.Equal(t, syscall.EPERM, e)
+		assert.ErrorIs(t, e, syscall.EPERM)
 	})
 
 	t.Run("Read on closed file return error", func(t *testing.T) {
@@ -202,7 +197,7 @@ func TesFileRead(t *testing.T) {
 		_, e := f.Read(p)
 
 		assert.Error(t, e)
-		assert.Equal(t, syscall.EPERM, e)
+		assert.ErrorIs(t, e, syscall.EPERM)
 	})
 
 	t.Run("Successful read updates offset", func(t *testing.T) {
@@ -271,7 +266,7 @@ func TestFileReadAt(t *testing.T) {
 		off := int64(5)
 		fs.manager.(*mocks.MockObjectManager).
 			EXPECT().
-			GetObjectPart(f.fs.ctx, f.fs.bucketName, f.name, off, off+int64(len(p))).
+			GetObjectPart(f.fs.ctx, f.fs.bucketName, f.name, off, off+int64(len(p))-1).
 			Return(strings.NewReader("test result"), cu, nil)
 
 		n, e := f.ReadAt(p, off)
@@ -290,7 +285,7 @@ func TestFileSeek(t *testing.T) {
 
 		_, err := f.Seek(0, io.SeekStart)
 		assert.Error(t, err)
-		assert.Equal(t, syscall.EPERM, err)
+		assert.ErrorIs(t, err, syscall.EPERM)
 	})
 
 	t.Run("Seek on directory returns error", func(t *testing.T) {
@@ -300,7 +295,7 @@ func TestFileSeek(t *testing.T) {
 
 		_, err := f.Seek(0, io.SeekStart)
 		assert.Error(t, err)
-		assert.Equal(t, syscall.EPERM, err)
+		assert.ErrorIs(t, err, syscall.EPERM)
 	})
 
 	t.Run("Seek with invalid whence returns error", func(t *testing.T) {
@@ -330,7 +325,7 @@ func TestFileSeek(t *testing.T) {
 
 		_, err := f.Seek(101, io.SeekStart)
 		assert.Error(t, err)
-		assert.Equal(t, afero.ErrOutOfRange, err)
+		assert.ErrorIs(t, err, afero.ErrOutOfRange)
 	})
 
 	t.Run("Seek to negative offset returns error", func(t *testing.T) {
@@ -345,7 +340,7 @@ func TestFileSeek(t *testing.T) {
 
 		_, err := f.Seek(-1, io.SeekStart)
 		assert.Error(t, err)
-		assert.Equal(t, afero.ErrOutOfRange, err)
+		assert.ErrorIs(t, err, afero.ErrOutOfRange)
 	})
 
 	t.Run("Successful SeekStart updates offset", func(t *testing.T) {
@@ -418,7 +413,7 @@ func TestFileDoWriteAt(t *testing.T) {
 		_, e := f.doWriteAt(p, 0)
 
 		assert.Error(t, e)
-		assert.Equal(t, syscall.EPERM, e)
+		assert.ErrorIs(t, e, syscall.EPERM)
 	})
 
 	t.Run("WriteAt with preload error", func(t *testing.T) {
@@ -460,10 +455,10 @@ func TestFileDoWriteAt(t *testing.T) {
 
 		assert.Nil(t, e)
 		assert.Equal(t, len(p), n)
-		assert.True(t, f.dirty)
+		assert.True(t, f.shared.dirty)
 
-		f.preloadedFd.Seek(0, io.SeekStart)
-		s, _ := io.ReadAll(f.preloadedFd)
+		f.shared.preloadedFd.Seek(0, io.SeekStart)
+		s, _ := io.ReadAll(f.shared.preloadedFd)
 		assert.Equal(t, "test data", string(s))
 	})
 
@@ -489,8 +484,8 @@ func TestFileDoWriteAt(t *testing.T) {
 		assert.Equal(t, 4, n)
 		assert.NoError(t, e)
 
-		f.preloadedFd.Seek(0, io.SeekStart)
-		s, _ := io.ReadAll(f.preloadedFd)
+		f.shared.preloadedFd.Seek(0, io.SeekStart)
+		s, _ := io.ReadAll(f.shared.preloadedFd)
 
 		assert.Equal(t, "abABCDg", string(s))
 	})
@@ -505,7 +500,7 @@ func TestFileWrite(t *testing.T) {
 		_, e := f.Write(p)
 
 		assert.Error(t, e)
-		assert.Equal(t, syscall.EPERM, e)
+		assert.ErrorIs(t, e, syscall.EPERM)
 	})
 
 	t.Run("Write on directory return error", func(t *testing.T) {
@@ -517,7 +512,7 @@ func TestFileWrite(t *testing.T) {
 		_, e := f.Write(p)
 
 		assert.Error(t, e)
-		assert.Equal(t, syscall.EPERM, e)
+		assert.ErrorIs(t, e, syscall.EPERM)
 	})
 
 	t.Run("Write on closed file return error", func(t *testing.T) {
@@ -529,7 +524,7 @@ func TestFileWrite(t *testing.T) {
 		_, e := f.Write(p)
 
 		assert.Error(t, e)
-		assert.Equal(t, syscall.EPERM, e)
+		assert.ErrorIs(t, e, syscall.EPERM)
 	})
 
 	t.Run("Successful write updates offset", func(t *testing.T) {
@@ -554,10 +549,10 @@ func TestFileWrite(t *testing.T) {
 		assert.NoError(t, err)
 		assert.Equal(t, 8, n)
 		assert.Equal(t, int64(8), f.offset)
-		assert.True(t, f.dirty)
+		assert.True(t, f.shared.dirty)
 
-		f.preloadedFd.Seek(0, io.SeekStart)
-		s, _ := io.ReadAll(f.preloadedFd)
+		f.shared.preloadedFd.Seek(0, io.SeekStart)
+		s, _ := io.ReadAll(f.shared.preloadedFd)
 		assert.Equal(t, "testdata", string(s))
 	})
 
@@ -594,8 +589,8 @@ func TestFileWrite(t *testing.T) {
 		assert.Equal(t, 4, n)
 		assert.Equal(t, int64(0), f.offset)
 
-		f.preloadedFd.Seek(0, io.SeekStart)
-		s, _ := io.ReadAll(f.preloadedFd)
+		f.shared.preloadedFd.Seek(0, io.SeekStart)
+		s, _ := io.ReadAll(f.shared.preloadedFd)
 		assert.Equal(t, originalContent+"data", string(s))
 	})
 }
@@ -609,7 +604,7 @@ func TestFileWriteAt(t *testing.T) {
 		_, e := f.WriteAt(p, 0)
 
 		assert.Error(t, e)
-		assert.Equal(t, syscall.EPERM, e)
+		assert.ErrorIs(t, e, syscall.EPERM)
 	})
 
 	t.Run("WriteAt on directory return error", func(t *testing.T) {
@@ -621,7 +616,7 @@ func TestFileWriteAt(t *testing.T) {
 		_, e := f.WriteAt(p, 0)
 
 		assert.Error(t, e)
-		assert.Equal(t, syscall.EPERM, e)
+		assert.ErrorIs(t, e, syscall.EPERM)
 	})
 
 	t.Run("WriteAt on closed file return error", func(t *testing.T) {
@@ -633,7 +628,7 @@ func TestFileWriteAt(t *testing.T) {
 		_, e := f.WriteAt(p, 0)
 
 		assert.Error(t, e)
-		assert.Equal(t, syscall.EPERM, e)
+		assert.ErrorIs(t, e, syscall.EPERM)
 	})
 
 	t.Run("WriteAt with append flag return error", func(t *testing.T) {
@@ -644,7 +639,7 @@ func TestFileWriteAt(t *testing.T) {
 		_, e := f.WriteAt(p, 0)
 
 		assert.Error(t, e)
-		assert.Equal(t, syscall.EPERM, e)
+		assert.ErrorIs(t, e, syscall.EPERM)
 	})
 
 	t.Run("Successful WriteAt updates content at offset", func(t *testing.T) {
@@ -669,10 +664,10 @@ func TestFileWriteAt(t *testing.T) {
 
 		assert.NoError(t, err)
 		assert.Equal(t, 4, n)
-		assert.True(t, f.dirty)
+		assert.True(t, f.shared.dirty)
 
-		f.preloadedFd.Seek(0, io.SeekStart)
-		s, _ := io.ReadAll(f.preloadedFd)
+		f.shared.preloadedFd.Seek(0, io.SeekStart)
+		s, _ := io.ReadAll(f.shared.preloadedFd)
 		assert.Equal(t, "originaltesttent", string(s))
 	})
 
@@ -698,7 +693,7 @@ func TestFileReaddir(t *testing.T) {
 		_, e := f.Readdir(10)
 
 		assert.Error(t, e)
-		assert.Equal(t, syscall.EPERM, e)
+		assert.ErrorIs(t, e, syscall.EPERM)
 	})
 
 	t.Run("Readdir on non-dir return error", func(t *testing.T) {
@@ -716,7 +711,9 @@ func TestFileReaddir(t *testing.T) {
 		f := getMockedFile("testdir/", os.O_RDONLY, fs)
 
 		fi1 := getMockedFileInfo(t)
+		fi1.EXPECT().Name().Return("testdir/file.txt")
 		fi2 := getMockedFileInfo(t)
+		fi2.EXPECT().Name().Return("testdir/subdir/")
 
 		expectedFis := []os.FileInfo{
 			fi1,
@@ -725,13 +722,102 @@ func TestFileReaddir(t *testing.T) {
 
 		fs.manager.(*mocks.MockObjectManager).
 			EXPECT().
-			ListObjects(fs.ctx, fs.bucketName, fs.ensureAsDir(f.name), 10).
-			Return(expectedFis, nil)
+			ListObjectsPage(fs.ctx, fs.bucketName, fs.ensureAsDir(f.name), "", 10).
+			Return(expectedFis, "", nil)
 
 		fis, e := f.Readdir(10)
 
 		assert.Nil(t, e)
 		assert.Equal(t, len(expectedFis), len(fis))
-		assert.Equal(t, expectedFis, fis)
+		assert.Equal(t, "file.txt", fis[0].Name())
+		assert.Equal(t, "subdir", fis[1].Name())
+	})
+
+	t.Run("Readdir includes subdirectories", func(t *testing.T) {
+		m := NewMemObjectManager()
+		for _, k := range []string{"testdir/", "testdir/a.txt", "testdir/sub/b.txt"} {
+			_, _ = m.PutObject(context.TODO(), "test-bucket", k, strings.NewReader(k))
+		}
+		f := getMockedFile("testdir/", os.O_RDONLY, NewOssFsWithManager(m, "test-bucket"))
+
+		fis, e := f.Readdir(0)
+
+		assert.Nil(t, e)
+		assert.Len(t, fis, 2)
+		assert.Equal(t, "a.txt", fis[0].Name())
+		assert.False(t, fis[0].IsDir())
+		assert.Equal(t, "sub", fis[1].Name())
+		assert.True(t, fis[1].IsDir())
+		assert.Equal(t, os.ModeDir, fis[1].Mode()&os.ModeDir)
+
+		f = getMockedFile("testdir/", os.O_RDONLY, f.fs)
+		names, e := f.Readdirnames(0)
+		assert.Nil(t, e)
+		assert.Equal(t, []string{"a.txt", "sub"}, names)
+	})
+
+	t.Run("Readdir continues in batches", func(t *testing.T) {
+		m := NewMemObjectManager()
+		for _, k := range []string{"testdir/", "testdir/a.txt", "testdir/b.txt", "testdir/c/d.txt", "testdir/e.txt", "testdir/f.txt"} {
+			_, _ = m.PutObject(context.TODO(), "test-bucket", k, strings.NewReader(k))
+		}
+		fs := NewOssFsWithManager(m, "test-bucket")
+
+		f := getMockedFile("testdir/", os.O_RDONLY, fs)
+		var names []string
+		for {
+			batch, e := f.Readdirnames(2)
+			if e == io.EOF {
+				break
+			}
+			assert.Nil(t, e)
+			assert.NotEmpty(t, batch)
+			assert.LessOrEqual(t, len(batch), 2)
+			names = append(names, batch...)
+		}
+		assert.Equal(t, []string{"a.txt", "b.txt", "c", "e.txt", "f.txt"}, names)
+
+		fis, e := f.Readdir(1)
+		assert.Equal(t, io.EOF, e)
+		assert.Empty(t, fis)
+		fis, e = f.Readdir(0)
+		assert.Nil(t, e)
+		assert.Empty(t, fis)
+
+		// the remaining entries are all read with n <= 0.
+		f = getMockedFile("testdir/", os.O_RDONLY, fs)
+		fis, e = f.Readdir(3)
+		assert.Nil(t, e)
+		assert.Len(t, fis, 3)
+		names, e = f.Readdirnames(-1)
+		assert.Nil(t, e)
+		assert.Equal(t, []string{"e.txt", "f.txt"}, names)
+	})
+
+	t.Run("Readdir returns io.EOF on empty directory", func(t *testing.T) {
+		m := NewMemObjectManager()
+		_, _ = m.PutObject(context.TODO(), "test-bucket", "testdir/", strings.NewReader(""))
+		f := getMockedFile("testdir/", os.O_RDONLY, NewOssFsWithManager(m, "test-bucket"))
+
+		fis, e := f.Readdir(10)
+		assert.Equal(t, io.EOF, e)
+		assert.Empty(t, fis)
+	})
+	t.Run("Readdir names entries by the custom separator", func(t *testing.T) {
+		m := NewMemObjectManager()
+		for _, name := range []string{"testdir:a.txt", "testdir:sub:", "testdir:sub:b.txt"} {
+			_, _ = m.PutObject(context.TODO(), "test-bucket", name, strings.NewReader(""))
+		}
+		fs := NewOssFsWithManager(m, "test-bucket", WithSeparator(":"))
+		f, err := fs.Open("testdir:")
+		assert.NoError(t, err)
+		fis, e := f.Readdir(-1)
+		assert.NoError(t, e)
+		dirs := make(map[string]bool)
+		for _, fi := range fis {
+			dirs[fi.Name()] = fi.IsDir()
+		}
+		assert.Equal(t, map[string]bool{"a.txt": false, "sub": true}, dirs)
+		assert.NoError(t, f.Close())
 	})
 }
@@ -0,0 +1,134 @@
+package ossfs
+
+import (
+	"context"
+	"errors"
+	"sync"
+)
+
+// flushConcurrency is the number of files synced or closed at the same time
+// by Flush and Close.
+const flushConcurrency = 8
+
+// ErrFsClosed is returned by the operations of a closed Fs, see Fs.Close.
+var ErrFsClosed = errors.New("ossfs: file system is closed")
+
+// checkClosed returns ErrFsClosed if the Fs is closed.
+func (fs *Fs) checkClosed() error {
+	fs.sharedMu.Lock()
+	defer fs.sharedMu.Unlock()
+	if fs.closed {
+		return ErrFsClosed
+	}
+	return nil
+}
+
+// register tracks the open handle until it's closed, so it's flushed and
+// closed by Flush and Close.
+func (fs *Fs) register(f *File) error {
+	fs.sharedMu.Lock()
+	defer fs.sharedMu.Unlock()
+	if fs.closed {
+		return ErrFsClosed
+	}
+	if fs.handles == nil {
+		fs.handles = make(map[*File]struct{})
+	}
+	fs.handles[f] = struct{}{}
+	return nil
+}
+
+// unregister stops tracking the closed handle.
+func (fs *Fs) unregister(f *File) {
+	fs.sharedMu.Lock()
+	defer fs.sharedMu.Unlock()
+	delete(fs.handles, f)
+}
+
+// Flush syncs every open file with writes not synced yet, i.e. the preloaded
+// files are uploaded and the streamed files wait for their uploading parts,
+// see File.Sync. At most flushConcurrency files are synced at the same time,
+// and the errors are joined. No more files are synced once ctx is done.
+func (fs *Fs) Flush(ctx context.Context) error {
+	fs.sharedMu.Lock()
+	if fs.closed {
+		fs.sharedMu.Unlock()
+		return ErrFsClosed
+	}
+	var syncs []func() error
+	for _, s := range fs.sharedFiles {
+		syncs = append(syncs, func() error {
+			return pathError("sync", s.name, fs.syncShared(s))
+		})
+	}
+	for f := range fs.handles {
+		syncs = append(syncs, f.syncWriter)
+	}
+	fs.sharedMu.Unlock()
+
+	return runConcurrently(ctx, syncs)
+}
+
+// Close flushes and closes every open file, and releases their preloaded
+// copies even if they failed to be synced, the errors are joined. After
+// Close, the operations of the Fs fail with ErrFsClosed, closing it again
+// does nothing.
+func (fs *Fs) Close() error {
+	fs.sharedMu.Lock()
+	if fs.closed {
+		fs.sharedMu.Unlock()
+		return nil
+	}
+	fs.closed = true
+	var closes []func() error
+	for f := range fs.handles {
+		closes = append(closes, func() error {
+			err := f.Close()
+			if err != nil {
+				f.abandon()
+			}
+			return err
+		})
+	}
+	fs.sharedMu.Unlock()
+
+	return runConcurrently(context.Background(), closes)
+}
+
+// runConcurrently runs fns with at most flushConcurrency of them at the same
+// time, and joins their errors. The remaining fns are not run once ctx is
+// done.
+func runConcurrently(ctx context.Context, fns []func() error) error {
+	var (
+		wg   sync.WaitGroup
+		mu   sync.Mutex
+		errs []error
+	)
+	sem := make(chan struct{}, flushConcurrency)
+	for _, fn := range fns {
+		select {
+		case sem <- struct{}{}:
+		case <-ctx.Done():
+		}
+		if err := ctx.Err(); err != nil {
+			mu.Lock()
+			errs = append(errs, err)
+			mu.Unlock()
+			break
+		}
+		wg.Add(1)
+		go func() {
+			defer func() {
+				<-sem
+				wg.Done()
+			}()
+			if err := fn(); err != nil {
+				mu.Lock()
+				errs = append(errs, err)
+				mu.Unlock()
+			}
+		}()
+	}
+	wg.Wait()
+	return errors.Join(errs...)
+}
@@ -0,0 +1,178 @@
+package ossfs
+
+import (
+	"context"
+	"errors"
+	"io"
+	"os"
+	"strings"
+	"sync/atomic"
+	"testing"
+
+	"github.com/spf13/afero"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/messikiller/afero-oss/internal/utils"
+)
+
+// failingPutManager fails the PutObject requests while fail is set, and
+// counts the requests.
+type failingPutManager struct {
+	*utils.MemObjectManager
+	fail atomic.Bool
+	puts atomic.Int32
+}
+
+var errPutFailed = errors.New("put failed")
+
+func (m *failingPutManager) PutObject(ctx context.Context, bucket, name string, r io.Reader) (bool, error) {
+	m.puts.Add(1)
+	if m.fail.Load() {
+		return false, errPutFailed
+	}
+	return m.MemObjectManager.PutObject(ctx, bucket, name, r)
+}
+
+func newFlushTestFs(t *testing.T) (*Fs, *failingPutManager) {
+	m := &failingPutManager{MemObjectManager: NewMemObjectManager()}
+	fs := NewOssFsWithManager(m, "test-bucket")
+	fs.syncPolicy = SyncPolicy{Mode: SyncOnClose}
+	for _, name := range []string{"a.txt", "b.txt"} {
+		_, err := m.MemObjectManager.PutObject(context.TODO(), "test-bucket", name, strings.NewReader(""))
+		assert.NoError(t, err)
+	}
+	return fs, m
+}
+
+func TestFsFlush(t *testing.T) {
+	t.Run("flush uploads pending writes", func(t *testing.T) {
+		fs, m := newFlushTestFs(t)
+		for _, name := range []string{"a.txt", "b.txt"} {
+			f, err := fs.OpenFile(name, os.O_RDWR, 0)
+			assert.NoError(t, err)
+			_, err = f.WriteString("data of " + name)
+			assert.NoError(t, err)
+		}
+		assert.Equal(t, "", readObjectForTest(t, fs.manager, "a.txt"))
+
+		assert.NoError(t, fs.Flush(context.TODO()))
+		assert.Equal(t, "data of a.txt", readObjectForTest(t, fs.manager, "a.txt"))
+		assert.Equal(t, "data of b.txt", readObjectForTest(t, fs.manager, "b.txt"))
+		assert.Equal(t, int32(2), m.puts.Load())
+
+		// Nothing is uploaded again without new writes.
+		assert.NoError(t, fs.Flush(context.TODO()))
+		assert.Equal(t, int32(2), m.puts.Load())
+	})
+
+	t.Run("flush streamed files", func(t *testing.T) {
+		fs := NewOssFsWithManager(NewMemObjectManager(), "test-bucket").WithMultipartUpload(100<<10, 2)
+		f, err := fs.OpenFile("large.bin", os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0)
+		assert.NoError(t, err)
+		_, err = f.Write(make([]byte, 300<<10))
+		assert.NoError(t, err)
+		assert.NoError(t, fs.Flush(context.TODO()))
+		assert.NoError(t, f.Close())
+		fi, err := fs.Stat("large.bin")
+		assert.NoError(t, err)
+		assert.Equal(t, int64(300<<10), fi.Size())
+	})
+
+	t.Run("flush errors are joined", func(t *testing.T) {
+		fs, m := newFlushTestFs(t)
+		f, err := fs.OpenFile("a.txt", os.O_RDWR, 0)
+		assert.NoError(t, err)
+		_, err = f.WriteString("data")
+		assert.NoError(t, err)
+
+		m.fail.Store(true)
+		err = fs.Flush(context.TODO())
+		assert.ErrorIs(t, err, errPutFailed)
+		assert.ErrorContains(t, err, "sync a.txt")
+
+		m.fail.Store(false)
+		assert.NoError(t, fs.Flush(context.TODO()))
+		assert.Equal(t, "data", readObjectForTest(t, fs.manager, "a.txt"))
+	})
+
+	t.Run("flush stops when context is done", func(t *testing.T) {
+		fs, _ := newFlushTestFs(t)
+		f, err := fs.OpenFile("a.txt", os.O_RDWR, 0)
+		assert.NoError(t, err)
+		_, err = f.WriteString("data")
+		assert.NoError(t, err)
+
+		ctx, cancel := context.WithCancel(context.TODO())
+		cancel()
+		assert.ErrorIs(t, fs.Flush(ctx), context.Canceled)
+		assert.Equal(t, "", readObjectForTest(t, fs.manager, "a.txt"))
+	})
+}
+
+func TestFsClose(t *testing.T) {
+	t.Run("close syncs and closes open files", func(t *testing.T) {
+		fs, _ := newFlushTestFs(t)
+		f, err := fs.OpenFile("a.txt", os.O_RDWR, 0)
+		assert.NoError(t, err)
+		_, err = f.WriteString("data")
+		assert.NoError(t, err)
+
+		assert.NoError(t, fs.Close())
+		assert.Equal(t, "data", readObjectForTest(t, fs.manager, "a.txt"))
+		_, err = f.WriteString("more")
+		assert.Error(t, err)
+		assert.NoError(t, f.Close())
+
+		existed, err := afero.Exists(fs.preloadFs, "a.txt")
+		assert.NoError(t, err)
+		assert.False(t, existed)
+		assert.Empty(t, fs.sharedFiles)
+		assert.Empty(t, fs.handles)
+	})
+
+	t.Run("closed handles are unregistered", func(t *testing.T) {
+		fs, _ := newFlushTestFs(t)
+		for _, name := range []string{"c.txt", "d.txt", "e.txt", "f.txt", "g.txt"} {
+			assert.NoError(t, afero.WriteFile(fs, name, []byte("data"), 0o644))
+		}
+		assert.Empty(t, fs.handles)
+		assert.Equal(t, "data", readObjectForTest(t, fs.manager, "g.txt"))
+	})
+
+	t.Run("operations fail after close", func(t *testing.T) {
+		fs, _ := newFlushTestFs(t)
+		assert.NoError(t, fs.Close())
+
+		_, err := fs.Stat("a.txt")
+		assert.ErrorIs(t, err, ErrFsClosed)
+		_, err = fs.Open("a.txt")
+		assert.ErrorIs(t, err, ErrFsClosed)
+		_, err = fs.Create("c.txt")
+		assert.ErrorIs(t, err, ErrFsClosed)
+		assert.ErrorIs(t, fs.Mkdir("dir", 0o755), ErrFsClosed)
+		assert.ErrorIs(t, fs.Remove("a.txt"), ErrFsClosed)
+		assert.ErrorIs(t, fs.RemoveAll("dir"), ErrFsClosed)
+		assert.ErrorIs(t, fs.Rename("a.txt", "c.txt"), ErrFsClosed)
+		for _, err := range fs.List(context.TODO(), "", true) {
+			assert.ErrorIs(t, err, ErrFsClosed)
+		}
+		assert.ErrorIs(t, fs.Flush(context.TODO()), ErrFsClosed)
+		assert.NoError(t, fs.Close())
+	})
+
+	t.Run("close releases files failed to be synced", func(t *testing.T) {
+		fs, m := newFlushTestFs(t)
+		f, err := fs.OpenFile("a.txt", os.O_RDWR, 0)
+		assert.NoError(t, err)
+		_, err = f.WriteString("data")
+		assert.NoError(t, err)
+
+		m.fail.Store(true)
+		assert.ErrorIs(t, fs.Close(), errPutFailed)
+		assert.Empty(t, fs.sharedFiles)
+		assert.Empty(t, fs.handles)
+		existed, err := afero.Exists(fs.preloadFs, "a.txt")
+		assert.NoError(t, err)
+		assert.False(t, existed)
+	})
+}
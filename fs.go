@@ -3,8 +3,11 @@ package ossfs
 import (
 	"context"
 	"errors"
+	"iter"
+	"log/slog"
 	"os"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/aliyun/alibabacloud-oss-go-sdk-v2/oss"
@@ -17,42 +20,127 @@ import (
 const (
 	defaultFileMode = 0o755                   // default file mode for creating new file.
 	defaultFileFlag = os.O_RDWR | os.O_CREATE // default flag for creating new file.
+
+	removeBatchSize   = 1000 // number of objects deleted by a request of RemoveAll.
+	removeConcurrency = 4    // number of concurrent delete requests of RemoveAll.
 )
 
 type Fs struct {
-	manager     utils.ObjectManager
-	bucketName  string
-	separator   string
-	autoSync    bool
-	openedFiles map[string]afero.File
-	preloadFs   afero.Fs
-	ctx         context.Context
-	ossCfg      *oss.Config
-}
-
-// NewOssFs creates a new ossfs.Fs object.
-func NewOssFs(accessKeyId, accessKeySecret, region, bucket string, ossOpts ...OSSOptionFunc) *Fs {
-	ossCfg := oss.LoadDefaultConfig().
-		WithCredentialsProvider(credentials.NewStaticCredentialsProvider(accessKeyId, accessKeySecret)).
-		WithRegion(region)
-
-	if len(ossOpts) > 0 {
-		for _, f := range ossOpts {
-			f(ossCfg)
-		}
+	manager    utils.ObjectManager
+	bucketName string
+	separator  string
+	preloadFs  afero.Fs
+	ctx        context.Context
+	ossCfg     *oss.Config
+
+	// The state shared by the handles written on the same file, see
+	// acquireShared, and the open handles, see register. The mutex also
+	// guards closed, see Close.
+	sharedFiles map[string]*sharedFile
+	handles     map[*File]struct{}
+	closed      bool
+	sharedMu    sync.Mutex
+
+	// The part size and concurrency of streaming multipart uploads, writes
+	// are not streamed if the part size is zero.
+	multipartPartSize    int64
+	multipartConcurrency int
+
+	// The checkpoint directory and part size of resumable uploads, see
+	// WithResumableUpload.
+	checkpointDir     string
+	resumablePartSize int64
+
+	// The chunk size and number of chunks to prefetch for sequential reads,
+	// see WithReadAhead.
+	readAheadSize   int64
+	readAheadChunks int
+
+	// The chunk size and number of workers of parallel ReadAt, see
+	// WithParallelRead.
+	parallelReadSize    int64
+	parallelReadWorkers int
+
+	// The cache of read blocks, see WithBlockCache.
+	cache *blockCache
+
+	// The cache of metadata, see WithMetadataCache.
+	metaCache *metaCache
+
+	// Whether the bucket has hierarchical namespace enabled, see
+	// WithHierarchicalNamespace.
+	hns bool
+
+	// Whether directory markers are never written, see
+	// WithoutDirectoryMarkers.
+	noDirMarkers bool
+
+	// When the writes of preloaded files are uploaded, see WithSyncPolicy.
+	syncPolicy SyncPolicy
+
+	// The logger of errors which cannot be returned, see WithLogger.
+	logger *slog.Logger
+
+	// The prefix the Fs is confined to, see WithKeyPrefix.
+	keyPrefix string
+}
+
+// NewOssFs creates a new ossfs.Fs object with a static access key pair, opts
+// configure the OSS client. See NewOssFsWithOptions to configure the Fs too.
+func NewOssFs(accessKeyId, accessKeySecret, region, bucket string, opts ...OSSOptionFunc) *Fs {
+	all := make([]Option, len(opts))
+	for i, opt := range opts {
+		all[i] = opt
 	}
+	return NewOssFsWithOptions(accessKeyId, accessKeySecret, region, bucket, all...)
+}
+
+// NewOssFsWithOptions creates a new ossfs.Fs object with a static access key
+// pair, opts are OSSOptionFuncs which configure the OSS client, and FsOptions
+// which configure the Fs. See NewOssFsWithCredentials for other credentials.
+func NewOssFsWithOptions(accessKeyId, accessKeySecret, region, bucket string, opts ...Option) *Fs {
+	provider := credentials.NewStaticCredentialsProvider(accessKeyId, accessKeySecret)
+	return NewOssFsWithCredentials(provider, region, bucket, opts...)
+}
 
-	return &Fs{
-		manager: &utils.OssObjectManager{
-			Client: oss.NewClient(ossCfg),
-		},
+// NewOssFsFromClient creates a new ossfs.Fs object which accesses the bucket
+// through an already configured OSS client.
+func NewOssFsFromClient(client *oss.Client, bucket string, opts ...FsOption) *Fs {
+	return NewOssFsWithManager(&utils.OssObjectManager{Client: client}, bucket, opts...)
+}
+
+// NewOssFsWithManager creates a new ossfs.Fs object which accesses the bucket
+// through the given ObjectManager, e.g. a MemObjectManager in tests.
+func NewOssFsWithManager(manager ObjectManager, bucket string, opts ...FsOption) *Fs {
+	fs := &Fs{
+		manager:     manager,
 		bucketName:  bucket,
 		separator:   "/",
-		autoSync:    true,
-		openedFiles: make(map[string]afero.File),
 		preloadFs:   afero.NewMemMapFs(),
 		ctx:         context.Background(),
-		ossCfg:      ossCfg,
+		sharedFiles: make(map[string]*sharedFile),
+		handles:     make(map[*File]struct{}),
+	}
+	for _, opt := range opts {
+		opt(fs)
+	}
+	fs.applyKeyPrefix()
+	fs.setDelimiter()
+	return fs
+}
+
+// setDelimiter makes the ObjectManagers of this package list directories by
+// the separator of the Fs, other ObjectManagers must do so by themselves.
+func (fs *Fs) setDelimiter() {
+	m := fs.manager
+	if p, ok := m.(*prefixManager); ok {
+		m = p.ObjectManager
+	}
+	switch m := m.(type) {
+	case *utils.OssObjectManager:
+		m.Delimiter = fs.separator
+	case *utils.MemObjectManager:
+		m.Delimiter = fs.separator
 	}
 }
 
@@ -69,52 +157,130 @@ func (fs *Fs) WithContext(ctx context.Context) *Fs {
 	return fs
 }
 
+// WithMultipartUpload enables streaming writes, a file opened as write-only
+// and written from empty, i.e. a new file or one opened with os.O_TRUNC, is
+// uploaded by a multipart upload of partSize bytes parts while it's written,
+// instead of being preloaded into the preload file system. At most
+// concurrency parts are uploaded at the same time.
+//
+// OSS requires the part size to be at least 100 KiB, and a streamed file
+// can only be written sequentially. A zero partSize disables streaming.
+func (fs *Fs) WithMultipartUpload(partSize int64, concurrency int) *Fs {
+	fs.multipartPartSize = max(partSize, 0)
+	fs.multipartConcurrency = max(concurrency, 1)
+	return fs
+}
+
 // Create creates a new empty file and open it, return the open file and error
 // if any happens.
-func (fs *Fs) Create(name string) (afero.File, error) {
+func (fs *Fs) Create(name string) (_ afero.File, err error) {
+	defer fs.wrapError("open", name, &err)
+	if err := fs.checkClosed(); err != nil {
+		return nil, err
+	}
+	if err := fs.checkFileName(name); err != nil {
+		return nil, err
+	}
 	n := fs.normFileName(name)
-	r := strings.NewReader("")
-	if _, err := fs.manager.PutObject(fs.ctx, fs.bucketName, n, r); err != nil {
+	if err := fs.create(n); err != nil {
+		return nil, err
+	}
+	f, err := NewOssFile(n, defaultFileFlag, fs)
+	if err != nil {
 		return nil, err
 	}
-	return NewOssFile(n, defaultFileFlag, fs)
+	if err := fs.register(f); err != nil {
+		return nil, err
+	}
+	return f, nil
+}
+
+// create puts the empty object of the normalized name, without opening it.
+func (fs *Fs) create(name string) error {
+	if fs.noDirMarkers && fs.isDir(name) {
+		return nil
+	}
+	r := strings.NewReader("")
+	if _, err := fs.manager.PutObject(fs.ctx, fs.bucketName, name, r); err != nil {
+		return err
+	}
+	fs.invalidateCache(name)
+	return nil
 }
 
 // Mkdir creates a directory in the filesystem, return an error if any
 // happens.
-func (fs *Fs) Mkdir(name string, perm os.FileMode) error {
+func (fs *Fs) Mkdir(name string, perm os.FileMode) (err error) {
+	defer fs.wrapError("mkdir", name, &err)
+	if err := fs.checkName(name); err != nil {
+		return err
+	}
+	if fs.normFileName(name) == "" {
+		// The root always exists.
+		return os.ErrExist
+	}
 	return fs.MkdirAll(fs.ensureAsDir(name), perm)
 }
 
 // MkdirAll creates a directory path and all parents that does not exist
 // yet.
-func (fs *Fs) MkdirAll(path string, perm os.FileMode) error {
+func (fs *Fs) MkdirAll(path string, perm os.FileMode) (err error) {
+	defer fs.wrapError("mkdir", path, &err)
+	if err := fs.checkClosed(); err != nil {
+		return err
+	}
+	if err := fs.checkName(path); err != nil {
+		return err
+	}
+	if fs.normFileName(path) == "" {
+		// The root always exists, it has no directory marker.
+		return nil
+	}
+	if fs.hns {
+		return fs.mkdirAllNative(path)
+	}
+	if fs.noDirMarkers {
+		return fs.mkdirPrefix(path)
+	}
 	dirName := fs.ensureAsDir(path)
 	r := strings.NewReader("")
-	_, err := fs.manager.PutObject(fs.ctx, fs.bucketName, dirName, r)
+	_, err = fs.manager.PutObject(fs.ctx, fs.bucketName, dirName, r)
+	fs.invalidateCache(dirName)
 	return err
 }
 
 // Open opens a file, returning it or an error, if any happens.
 func (fs *Fs) Open(name string) (afero.File, error) {
-	return fs.OpenFile(name, defaultFileFlag, defaultFileMode)
+	flag := defaultFileFlag
+	if fs.normFileName(name) == "" {
+		// The root always exists, and it can't be created.
+		flag = os.O_RDONLY
+	}
+	return fs.OpenFile(name, flag, defaultFileMode)
 }
 
-// OpenFile opens a file using the given flags and the given mode.
-func (fs *Fs) OpenFile(name string, flag int, perm os.FileMode) (afero.File, error) {
-	name = fs.normFileName(name)
-	file, found := fs.openedFiles[name]
-	if found && file.(*File).openFlag == flag {
-		return file, nil
+// OpenFile opens a file using the given flags and the given mode. Each call
+// returns an independent handle with its own offset, handles written on the
+// same file share the preloaded copy of it.
+func (fs *Fs) OpenFile(name string, flag int, perm os.FileMode) (_ afero.File, err error) {
+	defer fs.wrapError("open", name, &err)
+	if err := fs.checkClosed(); err != nil {
+		return nil, err
 	}
-
+	check := fs.checkName
+	if flag&(os.O_CREATE|os.O_TRUNC) != 0 {
+		check = fs.checkFileName
+	}
+	if err := check(name); err != nil {
+		return nil, err
+	}
+	name = fs.normFileName(name)
 	f, err := NewOssFile(name, flag, fs)
 	if err != nil {
 		return nil, err
 	}
 
-	existed := false
-	existed, err = fs.manager.IsObjectExist(fs.ctx, fs.bucketName, name)
+	existed, err := fs.fileExists(f)
 	if err != nil {
 		return nil, err
 	}
@@ -124,60 +290,193 @@ func (fs *Fs) OpenFile(name string, flag int, perm os.FileMode) (afero.File, err
 	}
 
 	if !existed && f.openFlag*os.O_CREATE != 0 {
-		if _, err := fs.Create(f.name); err != nil {
+		if err := fs.create(f.name); err != nil {
 			return nil, err
 		}
 	}
 
-	if f.openFlag&os.O_TRUNC != 0 {
+	if f.openFlag&os.O_TRUNC != 0 && (!fs.noDirMarkers || !f.isDir) {
 		_, err := f.fs.manager.PutObject(fs.ctx, fs.bucketName, f.name, strings.NewReader(""))
 		if err != nil {
 			return nil, err
 		}
+		fs.invalidateCache(f.name)
+		if err := fs.truncateShared(f.name); err != nil {
+			return nil, err
+		}
 	}
 
-	fs.openedFiles[name] = f
+	if fs.multipartPartSize > 0 && f.isWriteOnly() && !f.isAppendOnly() &&
+		(!existed || f.openFlag&os.O_TRUNC != 0) {
+		f.writer = newMultipartWriter(fs, f.name)
+	}
 
+	if err := fs.register(f); err != nil {
+		return nil, err
+	}
 	return f, nil
 }
 
 // Remove removes a file identified by name, returning an error, if any
 // happens.
-func (fs *Fs) Remove(name string) error {
-	return fs.manager.DeleteObject(fs.ctx, fs.bucketName, fs.normFileName(name))
+func (fs *Fs) Remove(name string) (err error) {
+	defer fs.wrapError("remove", name, &err)
+	if err := fs.checkClosed(); err != nil {
+		return err
+	}
+	if err := fs.checkFileName(name); err != nil {
+		return err
+	}
+	name = fs.normFileName(name)
+	if fs.noDirMarkers {
+		if err := fs.checkEmptyDir(name); err != nil {
+			return err
+		}
+	}
+	defer fs.invalidateCache(name)
+	return fs.manager.DeleteObject(fs.ctx, fs.bucketName, name)
 }
 
 // RemoveAll removes a directory path and any children it contains. It
-// does not fail if the path does not exist (return nil).
-func (fs *Fs) RemoveAll(path string) error {
-	dir := fs.ensureAsDir(path)
-	fis, err := fs.manager.ListAllObjects(fs.ctx, fs.bucketName, dir)
-	if err != nil {
+// does not fail if the path does not exist (return nil). The root of an Fs
+// confined by WithKeyPrefix cannot be removed.
+//
+// The listed objects are deleted in batches of removeBatchSize objects while
+// the listing continues, at most removeConcurrency batches at the same time,
+// and the objects failed to be deleted are reported in the joined error.
+func (fs *Fs) RemoveAll(path string) (err error) {
+	defer fs.wrapError("removeall", path, &err)
+	if err := fs.checkClosed(); err != nil {
+		return err
+	}
+	if err := fs.checkName(path); err != nil {
 		return err
 	}
-	for _, fi := range fis {
-		err = fs.manager.DeleteObject(fs.ctx, fs.bucketName, fi.Name())
+	if _, ok := fs.manager.(*prefixManager); ok && fs.normFileName(path) == "" {
+		// Removing the root of a prefixed Fs would remove the whole prefix.
+		return os.ErrPermission
+	}
+	dir := fs.ensureAsDir(path)
+
+	var (
+		wg   sync.WaitGroup
+		mu   sync.Mutex
+		errs []error
+	)
+	sem := make(chan struct{}, removeConcurrency)
+	remove := func(names []string) {
+		sem <- struct{}{}
+		wg.Add(1)
+		go func() {
+			defer func() {
+				<-sem
+				wg.Done()
+			}()
+			err := fs.manager.DeleteObjects(fs.ctx, fs.bucketName, names)
+			for _, name := range names {
+				fs.invalidateCache(name)
+			}
+			if err != nil {
+				mu.Lock()
+				errs = append(errs, err)
+				mu.Unlock()
+			}
+		}()
+	}
+
+	batch := make([]string, 0, removeBatchSize)
+	for fi, err := range fs.manager.IterObjects(fs.ctx, fs.bucketName, dir, true) {
 		if err != nil {
-			return err
+			mu.Lock()
+			errs = append(errs, err)
+			mu.Unlock()
+			break
+		}
+		batch = append(batch, fi.Name())
+		if len(batch) == removeBatchSize {
+			remove(batch)
+			batch = make([]string, 0, removeBatchSize)
 		}
 	}
-	return nil
+	if len(batch) > 0 {
+		remove(batch)
+	}
+	wg.Wait()
+
+	return errors.Join(errs...)
 }
 
-// Rename renames a file.
-func (fs *Fs) Rename(oldname, newname string) error {
-	err := fs.manager.CopyObject(fs.ctx, fs.bucketName, oldname, newname)
-	if err != nil {
+// List iterates the objects whose names have prefix, the entries are listed
+// lazily page by page, which suits prefixes holding a huge number of objects.
+// If recursive is false, only the objects and directories directly under
+// prefix are yielded, directories end with the separator and have ModeDir.
+// Otherwise all objects having prefix are yielded. Entries are named by their
+// full names, and an error is yielded as the last entry.
+func (fs *Fs) List(ctx context.Context, prefix string, recursive bool) iter.Seq2[os.FileInfo, error] {
+	if err := fs.checkClosed(); err != nil {
+		return func(yield func(os.FileInfo, error) bool) {
+			yield(nil, err)
+		}
+	}
+	return fs.manager.IterObjects(ctx, fs.bucketName, fs.normFileName(prefix), recursive)
+}
+
+// Rename renames a file, or a directory with all objects under it if oldname
+// names a directory, i.e. it ends with the separator, or there is no object
+// named oldname but objects under it.
+func (fs *Fs) Rename(oldname, newname string) (err error) {
+	defer fs.wrapError("rename", oldname, &err)
+	if err := fs.checkClosed(); err != nil {
 		return err
 	}
-	err = fs.manager.DeleteObject(fs.ctx, fs.bucketName, oldname)
-	return err
+	if err := fs.checkFileName(oldname); err != nil {
+		return err
+	}
+	if err := fs.checkFileName(newname); err != nil {
+		return err
+	}
+	if fs.hns {
+		return fs.renameNative(oldname, newname)
+	}
+	oldname, newname = fs.normFileName(oldname), fs.normFileName(newname)
+	if oldname == newname {
+		return nil
+	}
+	if !fs.isDir(oldname) {
+		existed, err := fs.objectExists(oldname)
+		if err != nil {
+			return err
+		}
+		if existed {
+			return fs.renameFile(oldname, newname)
+		}
+	}
+	return fs.renameDir(fs.ensureAsDir(oldname), fs.ensureAsDir(newname))
 }
 
 // Stat returns a FileInfo describing the named file, or an error, if any
-// happens.
-func (fs *Fs) Stat(name string) (os.FileInfo, error) {
-	fi, err := fs.manager.GetObjectMeta(fs.ctx, fs.bucketName, fs.normFileName(name))
+// happens. A name with neither an object nor a directory marker is a
+// directory if there are objects under it, so prefixes created by other tools
+// are directories too.
+func (fs *Fs) Stat(name string) (_ os.FileInfo, err error) {
+	defer fs.wrapError("stat", name, &err)
+	if err := fs.checkClosed(); err != nil {
+		return nil, err
+	}
+	if err := fs.checkName(name); err != nil {
+		return nil, err
+	}
+	name = fs.normFileName(name)
+	if name == "" {
+		return fs.statDir(name)
+	}
+	if fs.hns {
+		name = fs.trimDir(name)
+	}
+	fi, err := fs.stat(name)
+	if isNotFound(err) && !fs.hns {
+		return fs.statDir(name)
+	}
 	if err != nil {
 		return nil, err
 	}
@@ -192,15 +491,15 @@ func (fs *Fs) Name() string {
 
 // Chmod changes the mode of the named file to mode.
 func (fs *Fs) Chmod(name string, mode os.FileMode) error {
-	return errors.New("OSS: method Chmod is not implemented")
+	return pathError("chmod", name, errors.New("OSS: method Chmod is not implemented"))
 }
 
 // Chown changes the uid and gid of the named file.
 func (fs *Fs) Chown(name string, uid, gid int) error {
-	return errors.New("OSS: method Chown is not implemented")
+	return pathError("chown", name, errors.New("OSS: method Chown is not implemented"))
 }
 
 // Chtimes changes the access and modification times of the named file
 func (fs *Fs) Chtimes(name string, atime time.Time, mtime time.Time) error {
-	return errors.New("OSS: method Chtimes is not implemented")
+	return pathError("chtimes", name, errors.New("OSS: method Chtimes is not implemented"))
 }
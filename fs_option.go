@@ -0,0 +1,131 @@
+package ossfs
+
+import (
+	"context"
+	"log/slog"
+	"time"
+
+	"github.com/spf13/afero"
+)
+
+// Option configures NewOssFsWithOptions and NewOssFsWithCredentials, it's
+// either an OSSOptionFunc which configures the OSS client, or an FsOption
+// which configures the Fs.
+type Option interface {
+	isOption()
+}
+
+func (OSSOptionFunc) isOption() {}
+
+// FsOption configures the Fs, see NewOssFsWithOptions, NewOssFsFromClient and
+// NewOssFsWithManager.
+type FsOption func(fs *Fs)
+
+func (FsOption) isOption() {}
+
+// Specify the separator of directories in object names, it's "/" by default.
+// It also delimits the listings of the OSS client and of a MemObjectManager,
+// an ObjectManager of another type must list by the same separator.
+func WithSeparator(sep string) FsOption {
+	return func(fs *Fs) {
+		fs.separator = sep
+	}
+}
+
+// Specify the context of all operations, see Fs.WithContext.
+func WithContext(ctx context.Context) FsOption {
+	return func(fs *Fs) {
+		fs.WithContext(ctx)
+	}
+}
+
+// Specify the preload file system, see Fs.WithPreloadFs.
+func WithPreloadFs(pfs afero.Fs) FsOption {
+	return func(fs *Fs) {
+		fs.WithPreloadFs(pfs)
+	}
+}
+
+// Specify when the writes of preloaded files are uploaded, see
+// Fs.WithSyncPolicy.
+func WithSyncPolicy(policy SyncPolicy) FsOption {
+	return func(fs *Fs) {
+		fs.WithSyncPolicy(policy)
+	}
+}
+
+// Stream writes by multipart uploads, see Fs.WithMultipartUpload.
+func WithMultipartUpload(partSize int64, concurrency int) FsOption {
+	return func(fs *Fs) {
+		fs.WithMultipartUpload(partSize, concurrency)
+	}
+}
+
+// Make syncing large preloaded files resumable, see Fs.WithResumableUpload.
+func WithResumableUpload(checkpointDir string, partSize int64) FsOption {
+	return func(fs *Fs) {
+		fs.WithResumableUpload(checkpointDir, partSize)
+	}
+}
+
+// Prefetch chunks for sequential reads, see Fs.WithReadAhead.
+func WithReadAhead(chunkSize int64, chunks int) FsOption {
+	return func(fs *Fs) {
+		fs.WithReadAhead(chunkSize, chunks)
+	}
+}
+
+// Split large ReadAt into concurrent range requests, see
+// Fs.WithParallelRead.
+func WithParallelRead(chunkSize int64, workers int) FsOption {
+	return func(fs *Fs) {
+		fs.WithParallelRead(chunkSize, workers)
+	}
+}
+
+// Cache read blocks, see Fs.WithBlockCache.
+func WithBlockCache(store afero.Fs, blockSize, maxSize int64) FsOption {
+	return func(fs *Fs) {
+		fs.WithBlockCache(store, blockSize, maxSize)
+	}
+}
+
+// Cache metadata for a TTL, see Fs.WithMetadataCache.
+func WithMetadataCache(ttl time.Duration) FsOption {
+	return func(fs *Fs) {
+		fs.WithMetadataCache(ttl)
+	}
+}
+
+// Use the native operations of hierarchical namespace, see
+// Fs.WithHierarchicalNamespace.
+func WithHierarchicalNamespace() FsOption {
+	return func(fs *Fs) {
+		fs.WithHierarchicalNamespace()
+	}
+}
+
+// Never write directory markers, see Fs.WithoutDirectoryMarkers.
+func WithoutDirectoryMarkers() FsOption {
+	return func(fs *Fs) {
+		fs.WithoutDirectoryMarkers()
+	}
+}
+
+// Specify the logger of errors which cannot be returned, e.g. of background
+// write-back uploads, they are not logged by default. The request IDs of
+// missing objects, which are reported by fs.ErrNotExist, are logged at debug
+// level.
+func WithLogger(logger *slog.Logger) FsOption {
+	return func(fs *Fs) {
+		fs.logger = logger
+	}
+}
+
+// logError logs the error by the logger of the Fs if any.
+func (fs *Fs) logError(msg, name string, err error) {
+	if fs.logger == nil || err == nil {
+		return
+	}
+	fs.logger.Error(msg, slog.String("bucket", fs.bucketName), slog.String("name", name), slog.Any("error", err))
+}
@@ -0,0 +1,108 @@
+package ossfs
+
+import (
+	"bytes"
+	"context"
+	"log/slog"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/aliyun/alibabacloud-oss-go-sdk-v2/oss"
+	"github.com/aliyun/alibabacloud-oss-go-sdk-v2/oss/credentials"
+	"github.com/spf13/afero"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/messikiller/afero-oss/osstest"
+)
+
+type fsOptionTestKey struct{}
+
+func TestFsOptions(t *testing.T) {
+	ctx := context.WithValue(context.TODO(), fsOptionTestKey{}, "value")
+	pfs := afero.NewMemMapFs()
+	policy := SyncPolicy{Mode: SyncWriteBack, Delay: time.Second}
+	opts := []FsOption{
+		WithSeparator("|"),
+		WithContext(ctx),
+		WithPreloadFs(pfs),
+		WithSyncPolicy(policy),
+		WithMultipartUpload(1<<20, 2),
+		WithResumableUpload("/tmp/checkpoints", 8<<20),
+		WithReadAhead(1<<20, 3),
+		WithParallelRead(2<<20, 4),
+		WithBlockCache(nil, 1<<20, 8<<20),
+		WithMetadataCache(time.Minute),
+		WithHierarchicalNamespace(),
+		WithoutDirectoryMarkers(),
+	}
+
+	check := func(t *testing.T, fs *Fs) {
+		assert.Equal(t, "|", fs.separator)
+		assert.Equal(t, ctx, fs.ctx)
+		assert.Equal(t, pfs, fs.preloadFs)
+		assert.Equal(t, policy, fs.syncPolicy)
+		assert.Equal(t, int64(1<<20), fs.multipartPartSize)
+		assert.Equal(t, 2, fs.multipartConcurrency)
+		assert.Equal(t, "/tmp/checkpoints", fs.checkpointDir)
+		assert.Equal(t, int64(8<<20), fs.resumablePartSize)
+		assert.Equal(t, int64(1<<20), fs.readAheadSize)
+		assert.Equal(t, 3, fs.readAheadChunks)
+		assert.Equal(t, int64(2<<20), fs.parallelReadSize)
+		assert.Equal(t, 4, fs.parallelReadWorkers)
+		assert.NotNil(t, fs.cache)
+		assert.NotNil(t, fs.metaCache)
+		assert.True(t, fs.hns)
+		assert.True(t, fs.noDirMarkers)
+	}
+
+	t.Run("new fs with manager", func(t *testing.T) {
+		check(t, NewOssFsWithManager(NewMemObjectManager(), "test-bucket", opts...))
+	})
+
+	t.Run("new fs with oss and fs options", func(t *testing.T) {
+		all := []Option{OSSWithEndpoint("testEndpoint")}
+		for _, o := range opts {
+			all = append(all, o)
+		}
+		fs := NewOssFsWithOptions("ak", "sk", "cn-hangzhou", "test-bucket", all...)
+		check(t, fs)
+		assert.Equal(t, "testEndpoint", *fs.ossCfg.Endpoint)
+	})
+
+	t.Run("new fs from client", func(t *testing.T) {
+		srv := osstest.NewServer("test-bucket")
+		defer srv.Close()
+		client := oss.NewClient(oss.LoadDefaultConfig().
+			WithCredentialsProvider(credentials.NewStaticCredentialsProvider("ak", "sk")).
+			WithRegion("cn-hangzhou").
+			WithEndpoint(srv.URL).
+			WithUsePathStyle(true))
+
+		fs := NewOssFsFromClient(client, "test-bucket", WithSyncPolicy(SyncPolicy{Mode: SyncOnClose}))
+		assert.Equal(t, SyncOnClose, fs.syncPolicy.Mode)
+		assert.NoError(t, afero.WriteFile(fs, "a.txt", []byte("hello"), 0o644))
+		b, err := afero.ReadFile(fs, "a.txt")
+		assert.NoError(t, err)
+		assert.Equal(t, "hello", string(b))
+	})
+
+	t.Run("logger logs errors which cannot be returned", func(t *testing.T) {
+		var buf bytes.Buffer
+		fs, m := newFlushTestFs(t)
+		fs.WithSyncPolicy(SyncPolicy{})
+		WithLogger(slog.New(slog.NewTextHandler(&buf, nil)))(fs)
+
+		f, err := fs.OpenFile("a.txt", os.O_RDWR, 0)
+		assert.NoError(t, err)
+		m.fail.Store(true)
+		_, err = f.WriteString("data")
+		assert.NoError(t, err)
+		assert.Contains(t, buf.String(), "ossfs: sync failed")
+		assert.Contains(t, buf.String(), "name=a.txt")
+		assert.Contains(t, buf.String(), errPutFailed.Error())
+
+		m.fail.Store(false)
+		assert.NoError(t, f.Close())
+	})
+}
@@ -2,14 +2,21 @@ package ossfs
 
 import (
 	"context"
+	"errors"
+	"fmt"
+	"io"
+	"iter"
 	"os"
 	"strings"
+	"sync"
+	"syscall"
 	"testing"
 	"time"
 
 	"github.com/aliyun/alibabacloud-oss-go-sdk-v2/oss"
 	"github.com/spf13/afero"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
 
 	"github.com/messikiller/afero-oss/internal/mocks"
 )
@@ -36,12 +43,10 @@ func TestNewOssFs(t *testing.T) {
 				c.WithUserAgent("testUA")
 			}},
 			expected: &Fs{
-				bucketName:  "test-bucket",
-				separator:   "/",
-				autoSync:    true,
-				openedFiles: make(map[string]afero.File),
-				preloadFs:   afero.NewMemMapFs(),
-				ctx:         context.Background(),
+				bucketName: "test-bucket",
+				separator:  "/",
+				preloadFs:  afero.NewMemMapFs(),
+				ctx:        context.Background(),
 			},
 		},
 	}
@@ -52,8 +57,8 @@ func TestNewOssFs(t *testing.T) {
 			assert.NotNil(t, got.manager)
 			assert.Equal(t, tt.expected.bucketName, got.bucketName)
 			assert.Equal(t, tt.expected.separator, got.separator)
-			assert.Equal(t, tt.expected.autoSync, got.autoSync)
-			assert.NotNil(t, got.openedFiles)
+			assert.Equal(t, SyncImmediate, got.syncPolicy.Mode)
+			assert.NotNil(t, got.sharedFiles)
 			assert.NotNil(t, got.preloadFs)
 			assert.NotNil(t, got.ctx)
 			assert.Equal(t, "testEndpoint", *got.ossCfg.Endpoint)
@@ -62,6 +67,52 @@ func TestNewOssFs(t *testing.T) {
 	}
 }
 
+func TestNewOssFsWithManager(t *testing.T) {
+	m := NewMemObjectManager()
+	fs := NewOssFsWithManager(m, "test-bucket")
+
+	t.Run("fields are initialized", func(t *testing.T) {
+		assert.Equal(t, m, fs.manager)
+		assert.Equal(t, "test-bucket", fs.bucketName)
+		assert.Equal(t, "/", fs.separator)
+		assert.Equal(t, SyncImmediate, fs.syncPolicy.Mode)
+		assert.NotNil(t, fs.sharedFiles)
+		assert.NotNil(t, fs.preloadFs)
+		assert.NotNil(t, fs.ctx)
+		assert.Nil(t, fs.ossCfg)
+	})
+
+	t.Run("write and read through manager", func(t *testing.T) {
+		f, err := fs.Create("/path/to/test.txt")
+		assert.NoError(t, err)
+		n, err := f.WriteString("hello ossfs")
+		assert.NoError(t, err)
+		assert.Equal(t, 11, n)
+		assert.NoError(t, f.Close())
+
+		fi, err := fs.Stat("path/to/test.txt")
+		assert.NoError(t, err)
+		assert.Equal(t, int64(11), fi.Size())
+
+		f, err = fs.OpenFile("path/to/test.txt", os.O_RDONLY, defaultFileMode)
+		assert.NoError(t, err)
+		p := make([]byte, 5)
+		_, err = f.ReadAt(p, 6)
+		assert.NoError(t, err)
+		assert.Equal(t, "ossfs", string(p))
+	})
+
+	t.Run("rename and remove through manager", func(t *testing.T) {
+		assert.NoError(t, fs.Rename("path/to/test.txt", "path/to/renamed.txt"))
+		existed, _ := m.IsObjectExist(fs.ctx, "test-bucket", "path/to/test.txt")
+		assert.False(t, existed)
+
+		assert.NoError(t, fs.RemoveAll("path"))
+		fis, _ := m.ListAllObjects(fs.ctx, "test-bucket", "")
+		assert.Empty(t, fis)
+	})
+}
+
 func TestFsWithContext(t *testing.T) {
 	type bgMeta string
 	tests := []struct {
@@ -195,6 +246,14 @@ func TestFsMkdirAll(t *testing.T) {
 		assert.ErrorIs(t, err, afero.ErrFileClosed)
 		m.AssertExpectations(t)
 	})
+
+	t.Run("root already exists", func(t *testing.T) {
+		for _, name := range []string{"/", ".", ".."} {
+			assert.NoError(t, fs.MkdirAll(name, defaultFileMode), name)
+			assert.ErrorIs(t, fs.Mkdir(name, defaultFileMode), os.ErrExist, name)
+		}
+		m.AssertExpectations(t)
+	})
 }
 
 func TestFsOpenFile(t *testing.T) {
@@ -203,11 +262,10 @@ func TestFsOpenFile(t *testing.T) {
 	ctx := context.TODO()
 
 	fs := &Fs{
-		manager:     m,
-		bucketName:  bucket,
-		ctx:         ctx,
-		separator:   "/",
-		openedFiles: make(map[string]afero.File),
+		manager:    m,
+		bucketName: bucket,
+		ctx:        ctx,
+		separator:  "/",
 	}
 
 	t.Run("open existing file success", func(t *testing.T) {
@@ -227,6 +285,8 @@ func TestFsOpenFile(t *testing.T) {
 			IsObjectExist(ctx, bucket, "new.txt").
 			Return(false, nil).
 			Once()
+		m.EXPECT().GetObjectMeta(ctx, bucket, "new.txt/").Return(nil, os.ErrNotExist).Once()
+		m.EXPECT().ListObjectsPage(ctx, bucket, "new.txt/", "", 1).Return(nil, "", nil).Once()
 		m.EXPECT().
 			PutObject(ctx, bucket, "new.txt", strings.NewReader("")).
 			Return(true, nil).
@@ -254,13 +314,19 @@ func TestFsOpenFile(t *testing.T) {
 		m.AssertExpectations(t)
 	})
 
-	t.Run("open existing file from cache", func(t *testing.T) {
-		cachedFile := &File{name: "cached.txt", openFlag: os.O_RDONLY}
-		fs.openedFiles["cached.txt"] = cachedFile
-		file, err := fs.OpenFile("cached.txt", os.O_RDONLY, 0o644)
+	t.Run("open same file twice returns independent handles", func(t *testing.T) {
+		m.EXPECT().
+			IsObjectExist(ctx, bucket, "twice.txt").
+			Return(true, nil).
+			Twice()
+		file1, err := fs.OpenFile("twice.txt", os.O_RDONLY, 0o644)
 		assert.Nil(t, err)
-		assert.Equal(t, cachedFile, file)
-		assert.Implements(t, (*afero.File)(nil), file)
+		file2, err := fs.OpenFile("twice.txt", os.O_RDONLY, 0o644)
+		assert.Nil(t, err)
+		assert.NotSame(t, file1, file2)
+		assert.NoError(t, file1.Close())
+		assert.False(t, file2.(*File).closed)
+		m.AssertExpectations(t)
 	})
 
 	t.Run("open non-existing file without create flag fails", func(t *testing.T) {
@@ -268,6 +334,8 @@ func TestFsOpenFile(t *testing.T) {
 			IsObjectExist(ctx, bucket, "nonexist.txt").
 			Return(false, nil).
 			Once()
+		m.EXPECT().GetObjectMeta(ctx, bucket, "nonexist.txt/").Return(nil, os.ErrNotExist).Once()
+		m.EXPECT().ListObjectsPage(ctx, bucket, "nonexist.txt/", "", 1).Return(nil, "", nil).Once()
 		_, err := fs.OpenFile("nonexist.txt", os.O_RDONLY, 0o644)
 		assert.NotNil(t, err)
 		assert.ErrorIs(t, err, afero.ErrFileNotFound)
@@ -321,6 +389,20 @@ func TestFsRemove(t *testing.T) {
 	})
 }
 
+// seqOf returns an iterator of fis followed by err if it's not nil.
+func seqOf(fis []os.FileInfo, err error) iter.Seq2[os.FileInfo, error] {
+	return func(yield func(os.FileInfo, error) bool) {
+		for _, fi := range fis {
+			if !yield(fi, nil) {
+				return
+			}
+		}
+		if err != nil {
+			yield(nil, err)
+		}
+	}
+}
+
 func TestFsRemoveAll(t *testing.T) {
 	m := mocks.NewMockObjectManager(t)
 	bucket := "test-bucket"
@@ -341,10 +423,12 @@ func TestFsRemoveAll(t *testing.T) {
 			NewFileInfo("path/to/dir/subdir/", 0, time.Now()),
 		}
 
-		m.EXPECT().ListAllObjects(ctx, bucket, dirPath).Return(files, nil).Once()
-		m.EXPECT().DeleteObject(ctx, bucket, "path/to/dir/file1.txt").Return(nil).Once()
-		m.EXPECT().DeleteObject(ctx, bucket, "path/to/dir/file2.txt").Return(nil).Once()
-		m.EXPECT().DeleteObject(ctx, bucket, "path/to/dir/subdir/").Return(nil).Once()
+		m.EXPECT().IterObjects(ctx, bucket, dirPath, true).Return(seqOf(files, nil)).Once()
+		m.EXPECT().DeleteObjects(ctx, bucket, []string{
+			"path/to/dir/file1.txt",
+			"path/to/dir/file2.txt",
+			"path/to/dir/subdir/",
+		}).Return(nil).Once()
 
 		err := fs.RemoveAll(dirPath)
 		assert.Nil(t, err)
@@ -353,7 +437,7 @@ func TestFsRemoveAll(t *testing.T) {
 
 	t.Run("remove empty directory", func(t *testing.T) {
 		dirPath := "empty/dir/"
-		m.EXPECT().ListAllObjects(ctx, bucket, dirPath).Return([]os.FileInfo{}, nil).Once()
+		m.EXPECT().IterObjects(ctx, bucket, dirPath, true).Return(seqOf([]os.FileInfo{}, nil)).Once()
 
 		err := fs.RemoveAll(dirPath)
 		assert.Nil(t, err)
@@ -362,7 +446,7 @@ func TestFsRemoveAll(t *testing.T) {
 
 	t.Run("remove non-existent path", func(t *testing.T) {
 		nonExistentPath := "nonexistent/path/"
-		m.EXPECT().ListAllObjects(ctx, bucket, nonExistentPath).Return([]os.FileInfo{}, nil).Once()
+		m.EXPECT().IterObjects(ctx, bucket, nonExistentPath, true).Return(seqOf([]os.FileInfo{}, nil)).Once()
 
 		err := fs.RemoveAll(nonExistentPath)
 		assert.Nil(t, err)
@@ -371,7 +455,7 @@ func TestFsRemoveAll(t *testing.T) {
 
 	t.Run("list objects failure", func(t *testing.T) {
 		dirPath := "path/to/dir/"
-		m.EXPECT().ListAllObjects(ctx, bucket, dirPath).Return(nil, afero.ErrFileNotFound).Once()
+		m.EXPECT().IterObjects(ctx, bucket, dirPath, true).Return(seqOf(nil, afero.ErrFileNotFound)).Once()
 
 		err := fs.RemoveAll(dirPath)
 		assert.NotNil(t, err)
@@ -385,16 +469,94 @@ func TestFsRemoveAll(t *testing.T) {
 			NewFileInfo("path/to/dir/file1.txt", 0, time.Now()),
 		}
 
-		m.EXPECT().ListAllObjects(ctx, bucket, dirPath).Return(files, nil).Once()
-		m.EXPECT().DeleteObject(ctx, bucket, "path/to/dir/file1.txt").Return(afero.ErrFileNotFound).Once()
+		m.EXPECT().IterObjects(ctx, bucket, dirPath, true).Return(seqOf(files, nil)).Once()
+		m.EXPECT().DeleteObjects(ctx, bucket, []string{"path/to/dir/file1.txt"}).
+			Return(&DeleteError{Name: "path/to/dir/file1.txt", Err: afero.ErrFileNotFound}).Once()
 
 		err := fs.RemoveAll(dirPath)
 		assert.NotNil(t, err)
 		assert.ErrorIs(t, err, afero.ErrFileNotFound)
+		var derr *DeleteError
+		assert.ErrorAs(t, err, &derr)
+		assert.Equal(t, "path/to/dir/file1.txt", derr.Name)
+		m.AssertExpectations(t)
+	})
+
+	t.Run("delete in batches", func(t *testing.T) {
+		dirPath := "large/dir/"
+		files := make([]os.FileInfo, 0, 2500)
+		for i := range 2500 {
+			files = append(files, NewFileInfo(fmt.Sprintf("large/dir/%04d", i), 0, time.Now()))
+		}
+
+		var mu sync.Mutex
+		var deleted []string
+		m.EXPECT().IterObjects(ctx, bucket, dirPath, true).Return(seqOf(files, nil)).Once()
+		m.EXPECT().DeleteObjects(ctx, bucket, mock.Anything).
+			RunAndReturn(func(_ context.Context, _ string, names []string) error {
+				assert.LessOrEqual(t, len(names), 1000)
+				mu.Lock()
+				defer mu.Unlock()
+				deleted = append(deleted, names...)
+				if names[0] == "large/dir/1000" {
+					return errors.Join(
+						&DeleteError{Name: "large/dir/1001", Err: ErrNotDeleted},
+						&DeleteError{Name: "large/dir/1002", Err: ErrNotDeleted},
+					)
+				}
+				return nil
+			}).Times(3)
+
+		err := fs.RemoveAll(dirPath)
+		assert.ErrorIs(t, err, ErrNotDeleted)
+		assert.Contains(t, err.Error(), "large/dir/1001")
+		assert.Contains(t, err.Error(), "large/dir/1002")
+		assert.Len(t, deleted, 2500)
 		m.AssertExpectations(t)
 	})
 }
 
+func TestFsList(t *testing.T) {
+	m := NewMemObjectManager()
+	for _, k := range []string{"dir/", "dir/a.txt", "dir/sub/b.txt", "dir/sub/c.txt", "other.txt"} {
+		_, _ = m.PutObject(context.TODO(), "test-bucket", k, strings.NewReader(k))
+	}
+	fs := NewOssFsWithManager(m, "test-bucket")
+
+	list := func(prefix string, recursive bool) []string {
+		var names []string
+		for fi, err := range fs.List(context.TODO(), prefix, recursive) {
+			assert.NoError(t, err)
+			names = append(names, fi.Name())
+		}
+		return names
+	}
+
+	t.Run("list directly under prefix", func(t *testing.T) {
+		assert.Equal(t, []string{"dir/a.txt", "dir/sub/"}, list("/dir/", false))
+	})
+
+	t.Run("list recursively", func(t *testing.T) {
+		assert.Equal(t, []string{"dir/", "dir/a.txt", "dir/sub/b.txt", "dir/sub/c.txt"}, list("dir/", true))
+	})
+
+	t.Run("stop early", func(t *testing.T) {
+		var names []string
+		for fi := range fs.List(context.TODO(), "", true) {
+			names = append(names, fi.Name())
+			if len(names) == 2 {
+				break
+			}
+		}
+		assert.Equal(t, []string{"dir/", "dir/a.txt"}, names)
+	})
+
+	t.Run("remove while listing", func(t *testing.T) {
+		assert.NoError(t, fs.RemoveAll("dir"))
+		assert.Equal(t, []string{"other.txt"}, list("", true))
+	})
+}
+
 func TestFsRename(t *testing.T) {
 	m := mocks.NewMockObjectManager(t)
 	bucket := "test-bucket"
@@ -411,6 +573,7 @@ func TestFsRename(t *testing.T) {
 		oldname := "old/file.txt"
 		newname := "new/file.txt"
 
+		m.EXPECT().IsObjectExist(ctx, bucket, oldname).Return(true, nil).Once()
 		m.EXPECT().CopyObject(ctx, bucket, oldname, newname).Return(nil).Once()
 		m.EXPECT().DeleteObject(ctx, bucket, oldname).Return(nil).Once()
 
@@ -423,6 +586,7 @@ func TestFsRename(t *testing.T) {
 		oldname := "old/file.txt"
 		newname := "new/file.txt"
 
+		m.EXPECT().IsObjectExist(ctx, bucket, oldname).Return(true, nil).Once()
 		m.EXPECT().CopyObject(ctx, bucket, oldname, newname).Return(afero.ErrFileNotFound).Once()
 
 		err := fs.Rename(oldname, newname)
@@ -435,6 +599,7 @@ func TestFsRename(t *testing.T) {
 		oldname := "old/file.txt"
 		newname := "new/file.txt"
 
+		m.EXPECT().IsObjectExist(ctx, bucket, oldname).Return(true, nil).Once()
 		m.EXPECT().CopyObject(ctx, bucket, oldname, newname).Return(nil).Once()
 		m.EXPECT().DeleteObject(ctx, bucket, oldname).Return(afero.ErrFileNotFound).Once()
 
@@ -443,6 +608,75 @@ func TestFsRename(t *testing.T) {
 		assert.ErrorIs(t, err, afero.ErrFileNotFound)
 		m.AssertExpectations(t)
 	})
+
+	t.Run("names are normalized", func(t *testing.T) {
+		m.EXPECT().IsObjectExist(ctx, bucket, "old/file.txt").Return(true, nil).Once()
+		m.EXPECT().CopyObject(ctx, bucket, "old/file.txt", "new/file.txt").Return(nil).Once()
+		m.EXPECT().DeleteObject(ctx, bucket, "old/file.txt").Return(nil).Once()
+
+		assert.Nil(t, fs.Rename("/old/file.txt", "\\new\\file.txt"))
+		m.AssertExpectations(t)
+	})
+
+	t.Run("rename to itself", func(t *testing.T) {
+		assert.Nil(t, fs.Rename("old/file.txt", "/old/file.txt"))
+	})
+
+	t.Run("directory copy failure keeps source", func(t *testing.T) {
+		files := []os.FileInfo{
+			NewFileInfo("old/", 0, time.Now()),
+			NewFileInfo("old/a.txt", 1, time.Now()),
+		}
+		m.EXPECT().IterObjects(ctx, bucket, "old/", true).Return(seqOf(files, nil)).Once()
+		m.EXPECT().CopyObject(ctx, bucket, "old/", "new/").Return(nil).Once()
+		m.EXPECT().CopyObject(ctx, bucket, "old/a.txt", "new/a.txt").Return(afero.ErrFileNotFound).Once()
+
+		err := fs.Rename("old/", "new")
+		assert.ErrorIs(t, err, afero.ErrFileNotFound)
+		m.AssertExpectations(t)
+	})
+}
+
+func TestFsRenameDir(t *testing.T) {
+	m := NewMemObjectManager()
+	ctx := context.TODO()
+	for _, k := range []string{"old/", "old/a.txt", "old/sub/b.txt", "old/sub/c/d.txt", "older.txt"} {
+		_, _ = m.PutObject(ctx, "test-bucket", k, strings.NewReader(k))
+	}
+	fs := NewOssFsWithManager(m, "test-bucket")
+
+	keys := func() []string {
+		var names []string
+		for fi, err := range m.IterObjects(ctx, "test-bucket", "", true) {
+			assert.NoError(t, err)
+			names = append(names, fi.Name())
+		}
+		return names
+	}
+
+	t.Run("rename directory with marker", func(t *testing.T) {
+		assert.NoError(t, fs.Rename("old", "new"))
+		assert.Equal(t, []string{"new/", "new/a.txt", "new/sub/b.txt", "new/sub/c/d.txt", "older.txt"}, keys())
+
+		r, clean, err := m.GetObject(ctx, "test-bucket", "new/sub/c/d.txt")
+		assert.NoError(t, err)
+		b, _ := io.ReadAll(r)
+		clean()
+		assert.Equal(t, "old/sub/c/d.txt", string(b))
+	})
+
+	t.Run("rename directory without marker", func(t *testing.T) {
+		assert.NoError(t, fs.Rename("new/sub/", "moved/"))
+		assert.Equal(t, []string{"moved/b.txt", "moved/c/d.txt", "new/", "new/a.txt", "older.txt"}, keys())
+	})
+
+	t.Run("rename into itself", func(t *testing.T) {
+		assert.ErrorIs(t, fs.Rename("new", "new/inner"), syscall.EINVAL)
+	})
+
+	t.Run("rename missing", func(t *testing.T) {
+		assert.ErrorIs(t, fs.Rename("missing", "other"), afero.ErrFileNotFound)
+	})
 }
 
 func TestFsStat(t *testing.T) {
@@ -486,6 +720,8 @@ func TestFsStat(t *testing.T) {
 
 	t.Run("stat non-existent file", func(t *testing.T) {
 		m.EXPECT().GetObjectMeta(fs.ctx, bucket, "nonexistent.txt").Return(nil, os.ErrNotExist).Once()
+		m.EXPECT().GetObjectMeta(fs.ctx, bucket, "nonexistent.txt/").Return(nil, os.ErrNotExist).Once()
+		m.EXPECT().ListObjectsPage(fs.ctx, bucket, "nonexistent.txt/", "", 1).Return(nil, "", nil).Once()
 		_, err := fs.Stat("nonexistent.txt")
 		assert.NotNil(t, err)
 		assert.ErrorIs(t, err, os.ErrNotExist)
@@ -514,7 +750,7 @@ func TestFsChmod(t *testing.T) {
 	t.Run("chmod returns not implemented error", func(t *testing.T) {
 		err := fs.Chmod("test.txt", 0o644)
 		assert.NotNil(t, err)
-		assert.EqualError(t, err, "OSS: method Chmod is not implemented")
+		assert.EqualError(t, err, "chmod test.txt: OSS: method Chmod is not implemented")
 	})
 }
 
@@ -533,7 +769,7 @@ func TestFsChown(t *testing.T) {
 	t.Run("chown returns not implemented error", func(t *testing.T) {
 		err := fs.Chown("test.txt", 1000, 1000)
 		assert.NotNil(t, err)
-		assert.EqualError(t, err, "OSS: method Chown is not implemented")
+		assert.EqualError(t, err, "chown test.txt: OSS: method Chown is not implemented")
 	})
 }
 
@@ -553,6 +789,6 @@ func TestFsChtimes(t *testing.T) {
 		now := time.Now()
 		err := fs.Chtimes("test.txt", now, now)
 		assert.NotNil(t, err)
-		assert.EqualError(t, err, "OSS: method Chtimes is not implemented")
+		assert.EqualError(t, err, "chtimes test.txt: OSS: method Chtimes is not implemented")
 	})
 }
@@ -1,9 +1,15 @@
 package ossfs
 
 import (
+	iofs "io/fs"
+	"path"
 	"strings"
+	"unicode"
 )
 
+// maxKeyLength is the maximum length in bytes of object keys of OSS.
+const maxKeyLength = 1023
+
 func (fs *Fs) isDir(s string) bool {
 	sep := fs.separator
 	if fs.separator == "" {
@@ -24,13 +30,69 @@ func (fs *Fs) ensureAsDir(s string) string {
 	return s
 }
 
+// normFileName returns the key of name, which is cleaned as a rooted POSIX
+// path, so "a//b", "./a/b" and "a/../a/b" all name "a/b", and ".." cannot
+// go above the root. A name ending with a separator, "." or ".." names a
+// directory, and keeps the trailing separator.
 func (fs *Fs) normFileName(s string) string {
 	sep := fs.separator
 	if fs.separator == "" {
 		sep = "/"
 	}
-	s = strings.TrimLeft(s, "/\\")
-	s = strings.ReplaceAll(s, "\\", sep)
-	s = strings.ReplaceAll(s, "/", sep)
+	s = strings.ReplaceAll(s, "\\", "/")
+	isDir := strings.HasSuffix(s, "/") || path.Base(s) == "." || path.Base(s) == ".."
+	s = strings.TrimPrefix(path.Clean("/"+s), "/")
+	if isDir && s != "" {
+		s += "/"
+	}
+	return strings.ReplaceAll(s, "/", sep)
+}
+
+// checkName returns fs.ErrInvalid if name is empty, contains control
+// characters, or its key is longer than maxKeyLength bytes.
+func (fs *Fs) checkName(name string) error {
+	if name == "" || strings.ContainsFunc(name, unicode.IsControl) {
+		return iofs.ErrInvalid
+	}
+	if len(fs.normFileName(name)) > maxKeyLength {
+		return iofs.ErrInvalid
+	}
+	return nil
+}
+
+// checkFileName is checkName for the operations which write or remove the
+// object of name, it also returns fs.ErrInvalid if the key of name is empty,
+// e.g. of "/" or "..".
+func (fs *Fs) checkFileName(name string) error {
+	if err := fs.checkName(name); err != nil {
+		return err
+	}
+	if fs.normFileName(name) == "" {
+		return iofs.ErrInvalid
+	}
+	return nil
+}
+
+// baseName returns the last element of the name, without the trailing
+// separator of a directory.
+func (fs *Fs) baseName(s string) string {
+	sep := fs.separator
+	if fs.separator == "" {
+		sep = "/"
+	}
+	s = strings.TrimSuffix(s, sep)
+	if i := strings.LastIndex(s, sep); i >= 0 {
+		s = s[i+len(sep):]
+	}
 	return s
 }
+
+// trimDir returns the normalized name without the trailing separator, which
+// is how directories are named on buckets with hierarchical namespace.
+func (fs *Fs) trimDir(s string) string {
+	sep := fs.separator
+	if fs.separator == "" {
+		sep = "/"
+	}
+	return strings.TrimSuffix(fs.normFileName(s), sep)
+}
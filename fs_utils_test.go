@@ -0,0 +1,140 @@
+package ossfs
+
+import (
+	"context"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/spf13/afero"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFsNormFileName(t *testing.T) {
+	fs := &Fs{separator: "/"}
+	tests := map[string]string{
+		"a/b":        "a/b",
+		"/a/b":       "a/b",
+		"\\a\\b":     "a/b",
+		"a//b":       "a/b",
+		"./a/b":      "a/b",
+		"a/./b":      "a/b",
+		"a/../b":     "b",
+		"../../a":    "a",
+		"/..":        "",
+		"a/b/":       "a/b/",
+		"a/b/.":      "a/b/",
+		"a/b/..":     "a/",
+		"a/b//":      "a/b/",
+		"a/../../b/": "b/",
+		"/":          "",
+		".":          "",
+	}
+	for name, expected := range tests {
+		t.Run(name, func(t *testing.T) {
+			assert.Equal(t, expected, fs.normFileName(name))
+		})
+	}
+
+	t.Run("custom separator", func(t *testing.T) {
+		fs := &Fs{separator: ":"}
+		assert.Equal(t, "a:c:", fs.normFileName("/a/b/../c/"))
+	})
+}
+
+func TestFsCheckName(t *testing.T) {
+	fs := NewOssFsWithManager(NewMemObjectManager(), "test-bucket")
+
+	t.Run("invalid names", func(t *testing.T) {
+		for _, name := range []string{"", "a\nb", "a\x00b", "a\x7fb", strings.Repeat("a", maxKeyLength+1)} {
+			assert.ErrorIs(t, fs.checkName(name), os.ErrInvalid)
+		}
+		assert.NoError(t, fs.checkName(strings.Repeat("a", maxKeyLength)))
+		assert.NoError(t, fs.checkName("/"+strings.Repeat("a", maxKeyLength)))
+	})
+
+	t.Run("operations reject invalid names", func(t *testing.T) {
+		_, err := fs.Create("a\tb.txt")
+		assert.ErrorIs(t, err, os.ErrInvalid)
+		_, err = fs.Open("")
+		assert.ErrorIs(t, err, os.ErrInvalid)
+		_, err = fs.Stat("")
+		assert.ErrorIs(t, err, os.ErrInvalid)
+		assert.ErrorIs(t, fs.Mkdir("", 0o755), os.ErrInvalid)
+		assert.ErrorIs(t, fs.MkdirAll(strings.Repeat("a/", maxKeyLength), 0o755), os.ErrInvalid)
+		assert.ErrorIs(t, fs.Remove("a\rb"), os.ErrInvalid)
+		assert.ErrorIs(t, fs.RemoveAll(""), os.ErrInvalid)
+		assert.ErrorIs(t, fs.Rename("a.txt", "b\x00.txt"), os.ErrInvalid)
+	})
+
+	t.Run("operations reject empty keys", func(t *testing.T) {
+		for _, name := range []string{"/", ".", "..", "a/.."} {
+			assert.ErrorIs(t, fs.checkFileName(name), os.ErrInvalid, name)
+			_, err := fs.Create(name)
+			assert.ErrorIs(t, err, os.ErrInvalid, name)
+			for _, flag := range []int{os.O_RDWR | os.O_CREATE, os.O_WRONLY | os.O_TRUNC} {
+				_, err = fs.OpenFile(name, flag, 0o644)
+				assert.ErrorIs(t, err, os.ErrInvalid, name)
+			}
+			assert.ErrorIs(t, fs.Remove(name), os.ErrInvalid, name)
+			assert.ErrorIs(t, fs.Rename(name, "b.txt"), os.ErrInvalid, name)
+			assert.ErrorIs(t, fs.Rename("b.txt", name), os.ErrInvalid, name)
+		}
+		existed, err := fs.manager.IsObjectExist(context.TODO(), "test-bucket", "")
+		assert.NoError(t, err)
+		assert.False(t, existed)
+
+		f, err := fs.Open("/")
+		assert.NoError(t, err)
+		assert.NoError(t, f.Close())
+	})
+
+	t.Run("names are cleaned", func(t *testing.T) {
+		assert.NoError(t, afero.WriteFile(fs, "a/../b//c.txt", []byte("c"), 0o644))
+		b, err := afero.ReadFile(fs, "./b/./c.txt")
+		assert.NoError(t, err)
+		assert.Equal(t, "c", string(b))
+		fi, err := fs.Stat("../../b/c.txt")
+		assert.NoError(t, err)
+		assert.Equal(t, "b/c.txt", fi.Name())
+	})
+}
+
+func FuzzFsNormFileName(f *testing.F) {
+	for _, s := range []string{"a/b", "/a/../b/", "..\\..\\a", "./a//b/.", "a/b/.."} {
+		f.Add(s)
+	}
+	fs := &Fs{separator: "/"}
+	f.Fuzz(func(t *testing.T, name string) {
+		key := fs.normFileName(name)
+		assert.Equal(t, key, fs.normFileName(key), "not idempotent")
+		assert.False(t, strings.HasPrefix(key, "/"), "rooted key %q", key)
+		assert.NotContains(t, key, "\\")
+		assert.NotContains(t, key, "//")
+		for _, seg := range strings.Split(strings.TrimSuffix(key, "/"), "/") {
+			if key != "" {
+				assert.NotContains(t, []string{"", ".", ".."}, seg, "segment of key %q", key)
+			}
+		}
+	})
+}
+
+func FuzzFsCheckName(f *testing.F) {
+	for _, s := range []string{"a/b", "", "/", "a/..", "a\x00", strings.Repeat("ab/", 400)} {
+		f.Add(s)
+	}
+	fs := &Fs{separator: "/"}
+	f.Fuzz(func(t *testing.T, name string) {
+		if fs.checkName(name) != nil {
+			assert.Error(t, fs.checkFileName(name))
+			return
+		}
+		key := fs.normFileName(name)
+		assert.NotEmpty(t, name)
+		assert.Equal(t, key != "", fs.checkFileName(name) == nil)
+		assert.LessOrEqual(t, len(key), maxKeyLength)
+		for _, r := range key {
+			assert.False(t, r < 0x20 || r == 0x7f, "control character in key %q", key)
+		}
+	})
+}
@@ -0,0 +1,77 @@
+package ossfs
+
+import (
+	"context"
+	"os"
+	"strings"
+	"syscall"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFsHierarchicalNamespace(t *testing.T) {
+	m := NewMemObjectManager()
+	m.HierarchicalNamespace = true
+	fs := NewOssFsWithManager(m, "test-bucket").WithHierarchicalNamespace().WithMetadataCache(0)
+
+	t.Run("mkdir creates directories natively", func(t *testing.T) {
+		assert.NoError(t, fs.MkdirAll("/a/b/", 0o755))
+		for _, name := range []string{"a", "a/", "a/b"} {
+			fi, err := fs.Stat(name)
+			assert.NoError(t, err)
+			assert.True(t, fi.IsDir())
+		}
+		assert.NoError(t, fs.Mkdir("a/b", 0o755))
+	})
+
+	t.Run("open directory without trailing separator", func(t *testing.T) {
+		_, _ = m.PutObject(context.TODO(), "test-bucket", "a/b/c.txt", strings.NewReader("c"))
+		f, err := fs.Open("a")
+		assert.NoError(t, err)
+		fi, err := f.Stat()
+		assert.NoError(t, err)
+		assert.True(t, fi.IsDir())
+		names, err := f.Readdirnames(-1)
+		assert.NoError(t, err)
+		assert.Equal(t, []string{"b"}, names)
+		assert.NoError(t, f.Close())
+	})
+
+	t.Run("rename file", func(t *testing.T) {
+		assert.NoError(t, fs.Rename("a/b/c.txt", "/a/b/d.txt"))
+		_, err := fs.Stat("a/b/c.txt")
+		assert.ErrorIs(t, err, os.ErrNotExist)
+		_, err = fs.Stat("a/b/d.txt")
+		assert.NoError(t, err)
+	})
+
+	t.Run("rename directory", func(t *testing.T) {
+		_, err := fs.Stat("a/b/d.txt")
+		assert.NoError(t, err)
+		assert.NoError(t, fs.Rename("a/b/", "a/e"))
+		_, err = fs.Stat("a/b")
+		assert.ErrorIs(t, err, os.ErrNotExist)
+		_, err = fs.Stat("a/b/d.txt")
+		assert.ErrorIs(t, err, os.ErrNotExist)
+		_, err = fs.Stat("a/e/d.txt")
+		assert.NoError(t, err)
+	})
+
+	t.Run("rename to itself", func(t *testing.T) {
+		assert.NoError(t, fs.Rename("a/e", "a/e/"))
+	})
+
+	t.Run("rename directory into itself fails", func(t *testing.T) {
+		assert.ErrorIs(t, fs.Rename("a", "a/e/f"), syscall.EINVAL)
+	})
+
+	t.Run("rename missing file fails", func(t *testing.T) {
+		assert.Error(t, fs.Rename("missing", "other"))
+	})
+
+	t.Run("native operations fail on flat buckets", func(t *testing.T) {
+		fs := NewOssFsWithManager(NewMemObjectManager(), "test-bucket").WithHierarchicalNamespace()
+		assert.Error(t, fs.Mkdir("dir", 0o755))
+	})
+}
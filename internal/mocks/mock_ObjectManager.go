@@ -7,6 +7,7 @@ package mocks
 import (
 	"context"
 	"io"
+	"iter"
 	"os"
 
 	"github.com/messikiller/afero-oss/internal/utils"
@@ -40,6 +41,103 @@ func (_m *MockObjectManager) EXPECT() *MockObjectManager_Expecter {
 	return &MockObjectManager_Expecter{mock: &_m.Mock}
 }
 
+// AbortMultipartUpload provides a mock function for the type MockObjectManager
+func (_mock *MockObjectManager) AbortMultipartUpload(ctx context.Context, bucket string, name string, uploadId string) error {
+	ret := _mock.Called(ctx, bucket, name, uploadId)
+
+	if len(ret) == 0 {
+		panic("no return value specified for AbortMultipartUpload")
+	}
+
+	var r0 error
+	if returnFunc, ok := ret.Get(0).(func(context.Context, string, string, string) error); ok {
+		r0 = returnFunc(ctx, bucket, name, uploadId)
+	} else {
+		r0 = ret.Error(0)
+	}
+	return r0
+}
+
+// MockObjectManager_AbortMultipartUpload_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'AbortMultipartUpload'
+type MockObjectManager_AbortMultipartUpload_Call struct {
+	*mock.Call
+}
+
+// AbortMultipartUpload is a helper method to define mock.On call
+//   - ctx
+//   - bucket
+//   - name
+//   - uploadId
+func (_e *MockObjectManager_Expecter) AbortMultipartUpload(ctx interface{}, bucket interface{}, name interface{}, uploadId interface{}) *MockObjectManager_AbortMultipartUpload_Call {
+	return &MockObjectManager_AbortMultipartUpload_Call{Call: _e.mock.On("AbortMultipartUpload", ctx, bucket, name, uploadId)}
+}
+
+func (_c *MockObjectManager_AbortMultipartUpload_Call) Run(run func(ctx context.Context, bucket string, name string, uploadId string)) *MockObjectManager_AbortMultipartUpload_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		run(args[0].(context.Context), args[1].(string), args[2].(string), args[3].(string))
+	})
+	return _c
+}
+
+func (_c *MockObjectManager_AbortMultipartUpload_Call) Return(err error) *MockObjectManager_AbortMultipartUpload_Call {
+	_c.Call.Return(err)
+	return _c
+}
+
+func (_c *MockObjectManager_AbortMultipartUpload_Call) RunAndReturn(run func(ctx context.Context, bucket string, name string, uploadId string) error) *MockObjectManager_AbortMultipartUpload_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// CompleteMultipartUpload provides a mock function for the type MockObjectManager
+func (_mock *MockObjectManager) CompleteMultipartUpload(ctx context.Context, bucket string, name string, uploadId string, parts []utils.UploadedPart) error {
+	ret := _mock.Called(ctx, bucket, name, uploadId, parts)
+
+	if len(ret) == 0 {
+		panic("no return value specified for CompleteMultipartUpload")
+	}
+
+	var r0 error
+	if returnFunc, ok := ret.Get(0).(func(context.Context, string, string, string, []utils.UploadedPart) error); ok {
+		r0 = returnFunc(ctx, bucket, name, uploadId, parts)
+	} else {
+		r0 = ret.Error(0)
+	}
+	return r0
+}
+
+// MockObjectManager_CompleteMultipartUpload_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'CompleteMultipartUpload'
+type MockObjectManager_CompleteMultipartUpload_Call struct {
+	*mock.Call
+}
+
+// CompleteMultipartUpload is a helper method to define mock.On call
+//   - ctx
+//   - bucket
+//   - name
+//   - uploadId
+//   - parts
+func (_e *MockObjectManager_Expecter) CompleteMultipartUpload(ctx interface{}, bucket interface{}, name interface{}, uploadId interface{}, parts interface{}) *MockObjectManager_CompleteMultipartUpload_Call {
+	return &MockObjectManager_CompleteMultipartUpload_Call{Call: _e.mock.On("CompleteMultipartUpload", ctx, bucket, name, uploadId, parts)}
+}
+
+func (_c *MockObjectManager_CompleteMultipartUpload_Call) Run(run func(ctx context.Context, bucket string, name string, uploadId string, parts []utils.UploadedPart)) *MockObjectManager_CompleteMultipartUpload_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		run(args[0].(context.Context), args[1].(string), args[2].(string), args[3].(string), args[4].([]utils.UploadedPart))
+	})
+	return _c
+}
+
+func (_c *MockObjectManager_CompleteMultipartUpload_Call) Return(err error) *MockObjectManager_CompleteMultipartUpload_Call {
+	_c.Call.Return(err)
+	return _c
+}
+
+func (_c *MockObjectManager_CompleteMultipartUpload_Call) RunAndReturn(run func(ctx context.Context, bucket string, name string, uploadId string, parts []utils.UploadedPart) error) *MockObjectManager_CompleteMultipartUpload_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
 // CopyObject provides a mock function for the type MockObjectManager
 func (_mock *MockObjectManager) CopyObject(ctx context.Context, bucket string, srcName string, targetName string) error {
 	ret := _mock.Called(ctx, bucket, srcName, targetName)
@@ -88,6 +186,53 @@ func (_c *MockObjectManager_CopyObject_Call) RunAndReturn(run func(ctx context.C
 	return _c
 }
 
+// CreateDirectory provides a mock function for the type MockObjectManager
+func (_mock *MockObjectManager) CreateDirectory(ctx context.Context, bucket string, name string) error {
+	ret := _mock.Called(ctx, bucket, name)
+
+	if len(ret) == 0 {
+		panic("no return value specified for CreateDirectory")
+	}
+
+	var r0 error
+	if returnFunc, ok := ret.Get(0).(func(context.Context, string, string) error); ok {
+		r0 = returnFunc(ctx, bucket, name)
+	} else {
+		r0 = ret.Error(0)
+	}
+	return r0
+}
+
+// MockObjectManager_CreateDirectory_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'CreateDirectory'
+type MockObjectManager_CreateDirectory_Call struct {
+	*mock.Call
+}
+
+// CreateDirectory is a helper method to define mock.On call
+//   - ctx
+//   - bucket
+//   - name
+func (_e *MockObjectManager_Expecter) CreateDirectory(ctx interface{}, bucket interface{}, name interface{}) *MockObjectManager_CreateDirectory_Call {
+	return &MockObjectManager_CreateDirectory_Call{Call: _e.mock.On("CreateDirectory", ctx, bucket, name)}
+}
+
+func (_c *MockObjectManager_CreateDirectory_Call) Run(run func(ctx context.Context, bucket string, name string)) *MockObjectManager_CreateDirectory_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		run(args[0].(context.Context), args[1].(string), args[2].(string))
+	})
+	return _c
+}
+
+func (_c *MockObjectManager_CreateDirectory_Call) Return(err error) *MockObjectManager_CreateDirectory_Call {
+	_c.Call.Return(err)
+	return _c
+}
+
+func (_c *MockObjectManager_CreateDirectory_Call) RunAndReturn(run func(ctx context.Context, bucket string, name string) error) *MockObjectManager_CreateDirectory_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
 // DeleteObject provides a mock function for the type MockObjectManager
 func (_mock *MockObjectManager) DeleteObject(ctx context.Context, bucket string, name string) error {
 	ret := _mock.Called(ctx, bucket, name)
@@ -135,6 +280,53 @@ func (_c *MockObjectManager_DeleteObject_Call) RunAndReturn(run func(ctx context
 	return _c
 }
 
+// DeleteObjects provides a mock function for the type MockObjectManager
+func (_mock *MockObjectManager) DeleteObjects(ctx context.Context, bucket string, names []string) error {
+	ret := _mock.Called(ctx, bucket, names)
+
+	if len(ret) == 0 {
+		panic("no return value specified for DeleteObjects")
+	}
+
+	var r0 error
+	if returnFunc, ok := ret.Get(0).(func(context.Context, string, []string) error); ok {
+		r0 = returnFunc(ctx, bucket, names)
+	} else {
+		r0 = ret.Error(0)
+	}
+	return r0
+}
+
+// MockObjectManager_DeleteObjects_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'DeleteObjects'
+type MockObjectManager_DeleteObjects_Call struct {
+	*mock.Call
+}
+
+// DeleteObjects is a helper method to define mock.On call
+//   - ctx
+//   - bucket
+//   - names
+func (_e *MockObjectManager_Expecter) DeleteObjects(ctx interface{}, bucket interface{}, names interface{}) *MockObjectManager_DeleteObjects_Call {
+	return &MockObjectManager_DeleteObjects_Call{Call: _e.mock.On("DeleteObjects", ctx, bucket, names)}
+}
+
+func (_c *MockObjectManager_DeleteObjects_Call) Run(run func(ctx context.Context, bucket string, names []string)) *MockObjectManager_DeleteObjects_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		run(args[0].(context.Context), args[1].(string), args[2].([]string))
+	})
+	return _c
+}
+
+func (_c *MockObjectManager_DeleteObjects_Call) Return(err error) *MockObjectManager_DeleteObjects_Call {
+	_c.Call.Return(err)
+	return _c
+}
+
+func (_c *MockObjectManager_DeleteObjects_Call) RunAndReturn(run func(ctx context.Context, bucket string, names []string) error) *MockObjectManager_DeleteObjects_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
 // GetObject provides a mock function for the type MockObjectManager
 func (_mock *MockObjectManager) GetObject(ctx context.Context, bucket string, name string) (io.Reader, utils.CleanUp, error) {
 	ret := _mock.Called(ctx, bucket, name)
@@ -327,6 +519,62 @@ func (_c *MockObjectManager_GetObjectPart_Call) RunAndReturn(run func(ctx contex
 	return _c
 }
 
+// InitiateMultipartUpload provides a mock function for the type MockObjectManager
+func (_mock *MockObjectManager) InitiateMultipartUpload(ctx context.Context, bucket string, name string) (string, error) {
+	ret := _mock.Called(ctx, bucket, name)
+
+	if len(ret) == 0 {
+		panic("no return value specified for InitiateMultipartUpload")
+	}
+
+	var r0 string
+	var r1 error
+	if returnFunc, ok := ret.Get(0).(func(context.Context, string, string) (string, error)); ok {
+		return returnFunc(ctx, bucket, name)
+	}
+	if returnFunc, ok := ret.Get(0).(func(context.Context, string, string) string); ok {
+		r0 = returnFunc(ctx, bucket, name)
+	} else {
+		r0 = ret.Get(0).(string)
+	}
+	if returnFunc, ok := ret.Get(1).(func(context.Context, string, string) error); ok {
+		r1 = returnFunc(ctx, bucket, name)
+	} else {
+		r1 = ret.Error(1)
+	}
+	return r0, r1
+}
+
+// MockObjectManager_InitiateMultipartUpload_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'InitiateMultipartUpload'
+type MockObjectManager_InitiateMultipartUpload_Call struct {
+	*mock.Call
+}
+
+// InitiateMultipartUpload is a helper method to define mock.On call
+//   - ctx
+//   - bucket
+//   - name
+func (_e *MockObjectManager_Expecter) InitiateMultipartUpload(ctx interface{}, bucket interface{}, name interface{}) *MockObjectManager_InitiateMultipartUpload_Call {
+	return &MockObjectManager_InitiateMultipartUpload_Call{Call: _e.mock.On("InitiateMultipartUpload", ctx, bucket, name)}
+}
+
+func (_c *MockObjectManager_InitiateMultipartUpload_Call) Run(run func(ctx context.Context, bucket string, name string)) *MockObjectManager_InitiateMultipartUpload_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		run(args[0].(context.Context), args[1].(string), args[2].(string))
+	})
+	return _c
+}
+
+func (_c *MockObjectManager_InitiateMultipartUpload_Call) Return(s string, err error) *MockObjectManager_InitiateMultipartUpload_Call {
+	_c.Call.Return(s, err)
+	return _c
+}
+
+func (_c *MockObjectManager_InitiateMultipartUpload_Call) RunAndReturn(run func(ctx context.Context, bucket string, name string) (string, error)) *MockObjectManager_InitiateMultipartUpload_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
 // IsObjectExist provides a mock function for the type MockObjectManager
 func (_mock *MockObjectManager) IsObjectExist(ctx context.Context, bucket string, name string) (bool, error) {
 	ret := _mock.Called(ctx, bucket, name)
@@ -383,6 +631,56 @@ func (_c *MockObjectManager_IsObjectExist_Call) RunAndReturn(run func(ctx contex
 	return _c
 }
 
+// IterObjects provides a mock function for the type MockObjectManager
+func (_mock *MockObjectManager) IterObjects(ctx context.Context, bucket string, prefix string, recursive bool) iter.Seq2[os.FileInfo, error] {
+	ret := _mock.Called(ctx, bucket, prefix, recursive)
+
+	if len(ret) == 0 {
+		panic("no return value specified for IterObjects")
+	}
+
+	var r0 iter.Seq2[os.FileInfo, error]
+	if returnFunc, ok := ret.Get(0).(func(context.Context, string, string, bool) iter.Seq2[os.FileInfo, error]); ok {
+		r0 = returnFunc(ctx, bucket, prefix, recursive)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(iter.Seq2[os.FileInfo, error])
+		}
+	}
+	return r0
+}
+
+// MockObjectManager_IterObjects_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'IterObjects'
+type MockObjectManager_IterObjects_Call struct {
+	*mock.Call
+}
+
+// IterObjects is a helper method to define mock.On call
+//   - ctx
+//   - bucket
+//   - prefix
+//   - recursive
+func (_e *MockObjectManager_Expecter) IterObjects(ctx interface{}, bucket interface{}, prefix interface{}, recursive interface{}) *MockObjectManager_IterObjects_Call {
+	return &MockObjectManager_IterObjects_Call{Call: _e.mock.On("IterObjects", ctx, bucket, prefix, recursive)}
+}
+
+func (_c *MockObjectManager_IterObjects_Call) Run(run func(ctx context.Context, bucket string, prefix string, recursive bool)) *MockObjectManager_IterObjects_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		run(args[0].(context.Context), args[1].(string), args[2].(string), args[3].(bool))
+	})
+	return _c
+}
+
+func (_c *MockObjectManager_IterObjects_Call) Return(seq2 iter.Seq2[os.FileInfo, error]) *MockObjectManager_IterObjects_Call {
+	_c.Call.Return(seq2)
+	return _c
+}
+
+func (_c *MockObjectManager_IterObjects_Call) RunAndReturn(run func(ctx context.Context, bucket string, prefix string, recursive bool) iter.Seq2[os.FileInfo, error]) *MockObjectManager_IterObjects_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
 // ListAllObjects provides a mock function for the type MockObjectManager
 func (_mock *MockObjectManager) ListAllObjects(ctx context.Context, bucket string, prefix string) ([]os.FileInfo, error) {
 	ret := _mock.Called(ctx, bucket, prefix)
@@ -500,6 +798,72 @@ func (_c *MockObjectManager_ListObjects_Call) RunAndReturn(run func(ctx context.
 	return _c
 }
 
+// ListObjectsPage provides a mock function for the type MockObjectManager
+func (_mock *MockObjectManager) ListObjectsPage(ctx context.Context, bucket string, prefix string, token string, count int) ([]os.FileInfo, string, error) {
+	ret := _mock.Called(ctx, bucket, prefix, token, count)
+
+	if len(ret) == 0 {
+		panic("no return value specified for ListObjectsPage")
+	}
+
+	var r0 []os.FileInfo
+	var r1 string
+	var r2 error
+	if returnFunc, ok := ret.Get(0).(func(context.Context, string, string, string, int) ([]os.FileInfo, string, error)); ok {
+		return returnFunc(ctx, bucket, prefix, token, count)
+	}
+	if returnFunc, ok := ret.Get(0).(func(context.Context, string, string, string, int) []os.FileInfo); ok {
+		r0 = returnFunc(ctx, bucket, prefix, token, count)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]os.FileInfo)
+		}
+	}
+	if returnFunc, ok := ret.Get(1).(func(context.Context, string, string, string, int) string); ok {
+		r1 = returnFunc(ctx, bucket, prefix, token, count)
+	} else {
+		r1 = ret.Get(1).(string)
+	}
+	if returnFunc, ok := ret.Get(2).(func(context.Context, string, string, string, int) error); ok {
+		r2 = returnFunc(ctx, bucket, prefix, token, count)
+	} else {
+		r2 = ret.Error(2)
+	}
+	return r0, r1, r2
+}
+
+// MockObjectManager_ListObjectsPage_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'ListObjectsPage'
+type MockObjectManager_ListObjectsPage_Call struct {
+	*mock.Call
+}
+
+// ListObjectsPage is a helper method to define mock.On call
+//   - ctx
+//   - bucket
+//   - prefix
+//   - token
+//   - count
+func (_e *MockObjectManager_Expecter) ListObjectsPage(ctx interface{}, bucket interface{}, prefix interface{}, token interface{}, count interface{}) *MockObjectManager_ListObjectsPage_Call {
+	return &MockObjectManager_ListObjectsPage_Call{Call: _e.mock.On("ListObjectsPage", ctx, bucket, prefix, token, count)}
+}
+
+func (_c *MockObjectManager_ListObjectsPage_Call) Run(run func(ctx context.Context, bucket string, prefix string, token string, count int)) *MockObjectManager_ListObjectsPage_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		run(args[0].(context.Context), args[1].(string), args[2].(string), args[3].(string), args[4].(int))
+	})
+	return _c
+}
+
+func (_c *MockObjectManager_ListObjectsPage_Call) Return(vs []os.FileInfo, s string, err error) *MockObjectManager_ListObjectsPage_Call {
+	_c.Call.Return(vs, s, err)
+	return _c
+}
+
+func (_c *MockObjectManager_ListObjectsPage_Call) RunAndReturn(run func(ctx context.Context, bucket string, prefix string, token string, count int) ([]os.FileInfo, string, error)) *MockObjectManager_ListObjectsPage_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
 // PutObject provides a mock function for the type MockObjectManager
 func (_mock *MockObjectManager) PutObject(ctx context.Context, bucket string, name string, reader io.Reader) (bool, error) {
 	ret := _mock.Called(ctx, bucket, name, reader)
@@ -556,3 +920,110 @@ func (_c *MockObjectManager_PutObject_Call) RunAndReturn(run func(ctx context.Co
 	_c.Call.Return(run)
 	return _c
 }
+
+// RenameObject provides a mock function for the type MockObjectManager
+func (_mock *MockObjectManager) RenameObject(ctx context.Context, bucket string, srcName string, targetName string) error {
+	ret := _mock.Called(ctx, bucket, srcName, targetName)
+
+	if len(ret) == 0 {
+		panic("no return value specified for RenameObject")
+	}
+
+	var r0 error
+	if returnFunc, ok := ret.Get(0).(func(context.Context, string, string, string) error); ok {
+		r0 = returnFunc(ctx, bucket, srcName, targetName)
+	} else {
+		r0 = ret.Error(0)
+	}
+	return r0
+}
+
+// MockObjectManager_RenameObject_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'RenameObject'
+type MockObjectManager_RenameObject_Call struct {
+	*mock.Call
+}
+
+// RenameObject is a helper method to define mock.On call
+//   - ctx
+//   - bucket
+//   - srcName
+//   - targetName
+func (_e *MockObjectManager_Expecter) RenameObject(ctx interface{}, bucket interface{}, srcName interface{}, targetName interface{}) *MockObjectManager_RenameObject_Call {
+	return &MockObjectManager_RenameObject_Call{Call: _e.mock.On("RenameObject", ctx, bucket, srcName, targetName)}
+}
+
+func (_c *MockObjectManager_RenameObject_Call) Run(run func(ctx context.Context, bucket string, srcName string, targetName string)) *MockObjectManager_RenameObject_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		run(args[0].(context.Context), args[1].(string), args[2].(string), args[3].(string))
+	})
+	return _c
+}
+
+func (_c *MockObjectManager_RenameObject_Call) Return(err error) *MockObjectManager_RenameObject_Call {
+	_c.Call.Return(err)
+	return _c
+}
+
+func (_c *MockObjectManager_RenameObject_Call) RunAndReturn(run func(ctx context.Context, bucket string, srcName string, targetName string) error) *MockObjectManager_RenameObject_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// UploadPart provides a mock function for the type MockObjectManager
+func (_mock *MockObjectManager) UploadPart(ctx context.Context, bucket string, name string, uploadId string, partNumber int32, reader io.Reader) (utils.UploadedPart, error) {
+	ret := _mock.Called(ctx, bucket, name, uploadId, partNumber, reader)
+
+	if len(ret) == 0 {
+		panic("no return value specified for UploadPart")
+	}
+
+	var r0 utils.UploadedPart
+	var r1 error
+	if returnFunc, ok := ret.Get(0).(func(context.Context, string, string, string, int32, io.Reader) (utils.UploadedPart, error)); ok {
+		return returnFunc(ctx, bucket, name, uploadId, partNumber, reader)
+	}
+	if returnFunc, ok := ret.Get(0).(func(context.Context, string, string, string, int32, io.Reader) utils.UploadedPart); ok {
+		r0 = returnFunc(ctx, bucket, name, uploadId, partNumber, reader)
+	} else {
+		r0 = ret.Get(0).(utils.UploadedPart)
+	}
+	if returnFunc, ok := ret.Get(1).(func(context.Context, string, string, string, int32, io.Reader) error); ok {
+		r1 = returnFunc(ctx, bucket, name, uploadId, partNumber, reader)
+	} else {
+		r1 = ret.Error(1)
+	}
+	return r0, r1
+}
+
+// MockObjectManager_UploadPart_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'UploadPart'
+type MockObjectManager_UploadPart_Call struct {
+	*mock.Call
+}
+
+// UploadPart is a helper method to define mock.On call
+//   - ctx
+//   - bucket
+//   - name
+//   - uploadId
+//   - partNumber
+//   - reader
+func (_e *MockObjectManager_Expecter) UploadPart(ctx interface{}, bucket interface{}, name interface{}, uploadId interface{}, partNumber interface{}, reader interface{}) *MockObjectManager_UploadPart_Call {
+	return &MockObjectManager_UploadPart_Call{Call: _e.mock.On("UploadPart", ctx, bucket, name, uploadId, partNumber, reader)}
+}
+
+func (_c *MockObjectManager_UploadPart_Call) Run(run func(ctx context.Context, bucket string, name string, uploadId string, partNumber int32, reader io.Reader)) *MockObjectManager_UploadPart_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		run(args[0].(context.Context), args[1].(string), args[2].(string), args[3].(string), args[4].(int32), args[5].(io.Reader))
+	})
+	return _c
+}
+
+func (_c *MockObjectManager_UploadPart_Call) Return(uploadedPart utils.UploadedPart, err error) *MockObjectManager_UploadPart_Call {
+	_c.Call.Return(uploadedPart, err)
+	return _c
+}
+
+func (_c *MockObjectManager_UploadPart_Call) RunAndReturn(run func(ctx context.Context, bucket string, name string, uploadId string, partNumber int32, reader io.Reader) (utils.UploadedPart, error)) *MockObjectManager_UploadPart_Call {
+	_c.Call.Return(run)
+	return _c
+}
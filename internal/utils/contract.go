@@ -2,20 +2,73 @@ package utils
 
 import (
 	"context"
+	"errors"
+	"fmt"
 	"io"
+	"iter"
 	"os"
 )
 
 type CleanUp func()
 
+// UploadedPart identifies a part uploaded by ObjectManager.UploadPart, it's
+// needed to complete the multipart upload.
+type UploadedPart struct {
+	PartNumber int32
+	ETag       string
+}
+
+// ErrNotDeleted is the error of an object which a bulk deletion succeeded
+// without reporting it as deleted.
+var ErrNotDeleted = errors.New("object not deleted")
+
+// DeleteError reports an object which ObjectManager.DeleteObjects failed to
+// delete.
+type DeleteError struct {
+	Name string
+	Err  error
+}
+
+func (e *DeleteError) Error() string {
+	return fmt.Sprintf("delete %s: %v", e.Name, e.Err)
+}
+
+func (e *DeleteError) Unwrap() error {
+	return e.Err
+}
+
 type ObjectManager interface {
 	GetObject(ctx context.Context, bucket, name string) (io.Reader, CleanUp, error)
+	// GetObjectPart reads the inclusive range [start, end] of the object, or
+	// from start to the end of the object if end is negative.
 	GetObjectPart(ctx context.Context, bucket, name string, start, end int64) (io.Reader, CleanUp, error)
 	DeleteObject(ctx context.Context, bucket, name string) error
+	// DeleteObjects deletes the objects in requests of at most 1000 keys, every
+	// object not deleted is reported by a *DeleteError in the joined error.
+	DeleteObjects(ctx context.Context, bucket string, names []string) error
 	IsObjectExist(ctx context.Context, bucket, name string) (bool, error)
 	PutObject(ctx context.Context, bucket, name string, reader io.Reader) (bool, error)
 	CopyObject(ctx context.Context, bucket, srcName, targetName string) error
+	// RenameObject renames the object or directory srcName atomically, it's
+	// only supported by buckets with hierarchical namespace enabled, where
+	// directories are named without the trailing separator.
+	RenameObject(ctx context.Context, bucket, srcName, targetName string) error
+	// CreateDirectory creates the directory name on a bucket with hierarchical
+	// namespace enabled.
+	CreateDirectory(ctx context.Context, bucket, name string) error
 	GetObjectMeta(ctx context.Context, bucket, name string) (os.FileInfo, error)
 	ListObjects(ctx context.Context, bucket, prefix string, count int) ([]os.FileInfo, error)
+	// ListObjectsPage lists at most count (all if count <= 0) entries directly
+	// under prefix continuing from the page of token, it returns the token of
+	// the next page, which is empty after the last page.
+	ListObjectsPage(ctx context.Context, bucket, prefix, token string, count int) ([]os.FileInfo, string, error)
 	ListAllObjects(ctx context.Context, bucket, prefix string) ([]os.FileInfo, error)
+	// IterObjects iterates the objects having prefix lazily page by page, the
+	// entries directly under prefix as ListObjects if recursive is false, or
+	// all objects as ListAllObjects otherwise. An error ends the iteration.
+	IterObjects(ctx context.Context, bucket, prefix string, recursive bool) iter.Seq2[os.FileInfo, error]
+	InitiateMultipartUpload(ctx context.Context, bucket, name string) (string, error)
+	UploadPart(ctx context.Context, bucket, name, uploadId string, partNumber int32, reader io.Reader) (UploadedPart, error)
+	CompleteMultipartUpload(ctx context.Context, bucket, name, uploadId string, parts []UploadedPart) error
+	AbortMultipartUpload(ctx context.Context, bucket, name, uploadId string) error
 }
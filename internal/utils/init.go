@@ -3,7 +3,8 @@ package utils
 import "os"
 
 func init() {
-	// Ensure OssObjectManager implements ObjectManager interface
+	// Ensure object managers implement ObjectManager interface
 	var _ ObjectManager = (*OssObjectManager)(nil)
+	var _ ObjectManager = (*MemObjectManager)(nil)
 	var _ os.FileInfo = (*OssObjectMeta)(nil)
 }
@@ -0,0 +1,477 @@
+package utils
+
+import (
+	"bytes"
+	"cmp"
+	"context"
+	"crypto/md5"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"iter"
+	"net/http"
+	"os"
+	"slices"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/aliyun/alibabacloud-oss-go-sdk-v2/oss"
+	"github.com/spf13/afero"
+)
+
+// memListPageSize is the number of entries listed at a time by IterObjects.
+const memListPageSize = 1000
+
+// MemObjectManager is an in-memory implementation of ObjectManager. Objects of
+// every bucket are kept in process memory and listed in lexicographical order
+// just like OSS does, which makes it suitable for tests that should not talk
+// to a real bucket.
+type MemObjectManager struct {
+	mu      sync.RWMutex
+	buckets map[string]map[string]*memObject
+	uploads map[string]*memUpload
+	seq     int
+
+	// Now returns the time used as LastModified of written objects, it
+	// defaults to time.Now and can be replaced to get deterministic results.
+	Now func() time.Time
+
+	// HierarchicalNamespace emulates a bucket with hierarchical namespace
+	// enabled, which supports RenameObject and CreateDirectory. Directories
+	// are kept as directory markers, and they can be stated without the
+	// trailing separator.
+	HierarchicalNamespace bool
+
+	// Delimiter is the separator of directories in keys, which delimits
+	// listings and ends the names of directories, it's "/" if empty.
+	Delimiter string
+}
+
+type memObject struct {
+	data         []byte
+	etag         string
+	lastModified time.Time
+}
+
+func (obj *memObject) meta(name, sep string) *OssObjectMeta {
+	return &OssObjectMeta{
+		name:           name,
+		size:           int64(len(obj.data)),
+		lastModifiedAt: obj.lastModified,
+		etag:           obj.etag,
+		sep:            sep,
+	}
+}
+
+// memUpload is an in-progress multipart upload.
+type memUpload struct {
+	bucket string
+	name   string
+	parts  map[int32][]byte
+}
+
+// NewMemObjectManager creates an empty MemObjectManager.
+func NewMemObjectManager() *MemObjectManager {
+	return &MemObjectManager{
+		buckets: make(map[string]map[string]*memObject),
+		uploads: make(map[string]*memUpload),
+		Now:     time.Now,
+	}
+}
+
+func (m *MemObjectManager) delimiter() string {
+	if m.Delimiter == "" {
+		return ossDirSeparator
+	}
+	return m.Delimiter
+}
+
+func (m *MemObjectManager) now() time.Time {
+	if m.Now == nil {
+		return time.Now().UTC()
+	}
+	return m.Now().UTC()
+}
+
+// bucket returns the objects of bucket, creating the bucket if create is set.
+// The caller must hold the lock.
+func (m *MemObjectManager) bucket(name string, create bool) map[string]*memObject {
+	b, found := m.buckets[name]
+	if !found && create {
+		if m.buckets == nil {
+			m.buckets = make(map[string]map[string]*memObject)
+		}
+		b = make(map[string]*memObject)
+		m.buckets[name] = b
+	}
+	return b
+}
+
+func (m *MemObjectManager) lookup(bucket, name string) (*memObject, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	obj, found := m.bucket(bucket, false)[name]
+	if !found {
+		return nil, newMemNoSuchKeyError(bucket, name)
+	}
+	return obj, nil
+}
+
+func (m *MemObjectManager) store(bucket, name string, data []byte) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.bucket(bucket, true)[name] = &memObject{
+		data:         data,
+		etag:         memETag(data),
+		lastModified: m.now(),
+	}
+}
+
+func (m *MemObjectManager) GetObject(ctx context.Context, bucket, name string) (io.Reader, CleanUp, error) {
+	obj, err := m.lookup(bucket, name)
+	if err != nil {
+		return nil, nil, err
+	}
+	return bytes.NewReader(obj.data), func() {}, nil
+}
+
+// GetObjectPart reads the inclusive byte range [start, end] of the object, an
+// end beyond the object size is truncated as OSS does with the "standard"
+// range behavior, and a negative end reads to the end of the object.
+func (m *MemObjectManager) GetObjectPart(ctx context.Context, bucket, name string, start, end int64) (io.Reader, CleanUp, error) {
+	if end >= 0 && start > end {
+		return nil, nil, afero.ErrOutOfRange
+	}
+	obj, err := m.lookup(bucket, name)
+	if err != nil {
+		return nil, nil, err
+	}
+	size := int64(len(obj.data))
+	if start < 0 || start >= size {
+		return nil, nil, &oss.ServiceError{
+			StatusCode: http.StatusRequestedRangeNotSatisfiable,
+			Code:       "InvalidRange",
+			Message:    "The requested range cannot be satisfied.",
+		}
+	}
+	if end < 0 || end >= size {
+		end = size - 1
+	}
+	return bytes.NewReader(obj.data[start : end+1]), func() {}, nil
+}
+
+// DeleteObject removes the object, deleting a missing object is not an error.
+func (m *MemObjectManager) DeleteObject(ctx context.Context, bucket, name string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	delete(m.bucket(bucket, false), name)
+	return nil
+}
+
+func (m *MemObjectManager) DeleteObjects(ctx context.Context, bucket string, names []string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	objects := m.bucket(bucket, false)
+	for _, name := range names {
+		delete(objects, name)
+	}
+	return nil
+}
+
+func (m *MemObjectManager) IsObjectExist(ctx context.Context, bucket, name string) (bool, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	_, found := m.bucket(bucket, false)[name]
+	return found, nil
+}
+
+func (m *MemObjectManager) PutObject(ctx context.Context, bucket, name string, reader io.Reader) (bool, error) {
+	data, err := io.ReadAll(reader)
+	if err != nil {
+		return false, err
+	}
+	m.store(bucket, name, data)
+	return true, nil
+}
+
+func (m *MemObjectManager) CopyObject(ctx context.Context, bucket, srcName, targetName string) error {
+	obj, err := m.lookup(bucket, srcName)
+	if err != nil {
+		return err
+	}
+	m.store(bucket, targetName, bytes.Clone(obj.data))
+	return nil
+}
+
+func (m *MemObjectManager) GetObjectMeta(ctx context.Context, bucket, name string) (os.FileInfo, error) {
+	obj, err := m.lookup(bucket, name)
+	if err != nil && m.HierarchicalNamespace && !strings.HasSuffix(name, ossDirSeparator) {
+		if dir, e := m.lookup(bucket, name+ossDirSeparator); e == nil {
+			return dir.meta(name+ossDirSeparator, ossDirSeparator), nil
+		}
+	}
+	if err != nil {
+		return nil, err
+	}
+	return obj.meta(name, m.delimiter()), nil
+}
+
+// RenameObject moves the object, or all objects under the directory srcName,
+// to targetName atomically.
+func (m *MemObjectManager) RenameObject(ctx context.Context, bucket, srcName, targetName string) error {
+	if !m.HierarchicalNamespace {
+		return newMemNotSupportedError()
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	objects := m.bucket(bucket, false)
+	if obj, found := objects[srcName]; found {
+		delete(objects, srcName)
+		objects[targetName] = obj
+		return nil
+	}
+
+	srcDir, targetDir := srcName+ossDirSeparator, targetName+ossDirSeparator
+	var keys []string
+	for k := range objects {
+		if strings.HasPrefix(k, srcDir) {
+			keys = append(keys, k)
+		}
+	}
+	if len(keys) == 0 {
+		return newMemNoSuchKeyError(bucket, srcName)
+	}
+	for _, k := range keys {
+		objects[targetDir+strings.TrimPrefix(k, srcDir)] = objects[k]
+		delete(objects, k)
+	}
+	return nil
+}
+
+// CreateDirectory creates the directory as a directory marker.
+func (m *MemObjectManager) CreateDirectory(ctx context.Context, bucket, name string) error {
+	if !m.HierarchicalNamespace {
+		return newMemNotSupportedError()
+	}
+	m.store(bucket, name+ossDirSeparator, nil)
+	return nil
+}
+
+// ListObjects lists objects and directories directly under prefix, keys
+// containing another separator after the prefix are rolled up into a directory
+// like a listing delimited by the separator does. The directory marker object of the prefix
+// itself is not listed.
+func (m *MemObjectManager) ListObjects(ctx context.Context, bucket, prefix string, count int) ([]os.FileInfo, error) {
+	s, _ := m.list(bucket, prefix, m.delimiter(), "", count)
+	return s, nil
+}
+
+// ListObjectsPage lists the entries as ListObjects does after token, which is
+// the name of the last entry of the previous page.
+func (m *MemObjectManager) ListObjectsPage(ctx context.Context, bucket, prefix, token string, count int) ([]os.FileInfo, string, error) {
+	s, truncated := m.list(bucket, prefix, m.delimiter(), token, count)
+	if !truncated {
+		return s, "", nil
+	}
+	return s, s[len(s)-1].Name(), nil
+}
+
+func (m *MemObjectManager) ListAllObjects(ctx context.Context, bucket, prefix string) ([]os.FileInfo, error) {
+	s, _ := m.list(bucket, prefix, "", "", 0)
+	return s, nil
+}
+
+// IterObjects iterates the objects having prefix in pages of memListPageSize
+// entries, the lock is not held while the entries are yielded, so objects may
+// be changed during the iteration, e.g. deleted.
+func (m *MemObjectManager) IterObjects(ctx context.Context, bucket, prefix string, recursive bool) iter.Seq2[os.FileInfo, error] {
+	delimiter := m.delimiter()
+	if recursive {
+		delimiter = ""
+	}
+	return func(yield func(os.FileInfo, error) bool) {
+		after := ""
+		for {
+			s, truncated := m.list(bucket, prefix, delimiter, after, memListPageSize)
+			for _, fi := range s {
+				if !yield(fi, nil) {
+					return
+				}
+			}
+			if !truncated {
+				return
+			}
+			after = s[len(s)-1].Name()
+		}
+	}
+}
+
+// list returns at most count (all if count <= 0) objects having prefix after
+// the key after in lexicographical order, and whether there are more objects.
+// If delimiter is not empty only keys without a delimiter after the prefix are
+// returned.
+func (m *MemObjectManager) list(bucket, prefix, delimiter, after string, count int) ([]os.FileInfo, bool) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	objects := m.bucket(bucket, false)
+	keys := make([]string, 0, len(objects))
+	dirs := make(map[string]bool)
+	for k := range objects {
+		if !strings.HasPrefix(k, prefix) {
+			continue
+		}
+		if delimiter != "" {
+			if k == prefix {
+				continue
+			}
+			if i := strings.Index(k[len(prefix):], delimiter); i >= 0 {
+				dir := k[:len(prefix)+i+len(delimiter)]
+				if dir > after && !dirs[dir] {
+					dirs[dir] = true
+					keys = append(keys, dir)
+				}
+				continue
+			}
+		}
+		if k > after {
+			keys = append(keys, k)
+		}
+	}
+	sort.Strings(keys)
+
+	truncated := count > 0 && len(keys) > count
+	if truncated {
+		keys = keys[:count]
+	}
+
+	s := make([]os.FileInfo, 0, len(keys))
+	for _, k := range keys {
+		if dirs[k] {
+			s = append(s, NewOssDirMeta(k, m.delimiter()))
+			continue
+		}
+		s = append(s, objects[k].meta(k, m.delimiter()))
+	}
+	return s, truncated
+}
+
+func (m *MemObjectManager) InitiateMultipartUpload(ctx context.Context, bucket, name string) (string, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.uploads == nil {
+		m.uploads = make(map[string]*memUpload)
+	}
+	m.seq++
+	uploadId := fmt.Sprintf("%032X", m.seq)
+	m.uploads[uploadId] = &memUpload{
+		bucket: bucket,
+		name:   name,
+		parts:  make(map[int32][]byte),
+	}
+	return uploadId, nil
+}
+
+// upload returns the multipart upload, the caller must hold the lock.
+func (m *MemObjectManager) upload(bucket, name, uploadId string) (*memUpload, error) {
+	u, found := m.uploads[uploadId]
+	if !found || u.bucket != bucket || u.name != name {
+		return nil, &oss.ServiceError{
+			StatusCode: http.StatusNotFound,
+			Code:       "NoSuchUpload",
+			Message:    "The specified upload does not exist.",
+		}
+	}
+	return u, nil
+}
+
+func (m *MemObjectManager) UploadPart(ctx context.Context, bucket, name, uploadId string, partNumber int32, reader io.Reader) (UploadedPart, error) {
+	data, err := io.ReadAll(reader)
+	if err != nil {
+		return UploadedPart{}, err
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	u, err := m.upload(bucket, name, uploadId)
+	if err != nil {
+		return UploadedPart{}, err
+	}
+	u.parts[partNumber] = data
+	return UploadedPart{PartNumber: partNumber, ETag: memETag(data)}, nil
+}
+
+// CompleteMultipartUpload concatenates the given parts in order of part
+// number, every part must match the ETag returned by UploadPart.
+func (m *MemObjectManager) CompleteMultipartUpload(ctx context.Context, bucket, name, uploadId string, parts []UploadedPart) error {
+	m.mu.Lock()
+	u, err := m.upload(bucket, name, uploadId)
+	if err != nil {
+		m.mu.Unlock()
+		return err
+	}
+
+	sorted := slices.Clone(parts)
+	slices.SortFunc(sorted, func(a, b UploadedPart) int {
+		return cmp.Compare(a.PartNumber, b.PartNumber)
+	})
+	var buf bytes.Buffer
+	for _, p := range sorted {
+		data, found := u.parts[p.PartNumber]
+		if !found || memETag(data) != p.ETag {
+			m.mu.Unlock()
+			return &oss.ServiceError{
+				StatusCode: http.StatusBadRequest,
+				Code:       "InvalidPart",
+				Message:    "One or more of the specified parts could not be found or the specified entity tag might not have matched the part's entity tag.",
+			}
+		}
+		buf.Write(data)
+	}
+	delete(m.uploads, uploadId)
+	m.mu.Unlock()
+
+	m.store(bucket, name, buf.Bytes())
+	return nil
+}
+
+func (m *MemObjectManager) AbortMultipartUpload(ctx context.Context, bucket, name, uploadId string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if _, err := m.upload(bucket, name, uploadId); err != nil {
+		return err
+	}
+	delete(m.uploads, uploadId)
+	return nil
+}
+
+// MultipartUploads returns the number of multipart uploads neither completed
+// nor aborted yet.
+func (m *MemObjectManager) MultipartUploads() int {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return len(m.uploads)
+}
+
+func memETag(data []byte) string {
+	sum := md5.Sum(data)
+	return `"` + strings.ToUpper(hex.EncodeToString(sum[:])) + `"`
+}
+
+func newMemNotSupportedError() error {
+	return &oss.ServiceError{
+		StatusCode: http.StatusBadRequest,
+		Code:       "OperationNotSupported",
+		Message:    "The operation is not supported for this bucket.",
+	}
+}
+
+func newMemNoSuchKeyError(bucket, name string) error {
+	return &oss.ServiceError{
+		StatusCode:    http.StatusNotFound,
+		Code:          "NoSuchKey",
+		Message:       "The specified key does not exist.",
+		RequestTarget: bucket + "/" + name,
+	}
+}
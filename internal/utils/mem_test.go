@@ -0,0 +1,326 @@
+package utils
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/aliyun/alibabacloud-oss-go-sdk-v2/oss"
+	"github.com/stretchr/testify/assert"
+)
+
+func readAllForTest(t *testing.T, r io.Reader, clean CleanUp) string {
+	defer clean()
+	b, err := io.ReadAll(r)
+	assert.NoError(t, err)
+	return string(b)
+}
+
+func TestMemObjectManagerPutGet(t *testing.T) {
+	m := NewMemObjectManager()
+	ctx := context.TODO()
+	now := time.Date(2025, 1, 2, 3, 4, 5, 0, time.UTC)
+	m.Now = func() time.Time { return now }
+
+	t.Run("put and get object", func(t *testing.T) {
+		ok, err := m.PutObject(ctx, "bucket", "a/b.txt", strings.NewReader("hello world"))
+		assert.NoError(t, err)
+		assert.True(t, ok)
+
+		r, clean, err := m.GetObject(ctx, "bucket", "a/b.txt")
+		assert.NoError(t, err)
+		assert.Equal(t, "hello world", readAllForTest(t, r, clean))
+	})
+
+	t.Run("objects are isolated by bucket", func(t *testing.T) {
+		existed, err := m.IsObjectExist(ctx, "other-bucket", "a/b.txt")
+		assert.NoError(t, err)
+		assert.False(t, existed)
+
+		existed, err = m.IsObjectExist(ctx, "bucket", "a/b.txt")
+		assert.NoError(t, err)
+		assert.True(t, existed)
+	})
+
+	t.Run("get missing object returns NoSuchKey", func(t *testing.T) {
+		_, _, err := m.GetObject(ctx, "bucket", "missing")
+		var serr *oss.ServiceError
+		assert.True(t, errors.As(err, &serr))
+		assert.Equal(t, "NoSuchKey", serr.Code)
+		assert.Equal(t, http.StatusNotFound, serr.StatusCode)
+	})
+
+	t.Run("get object meta", func(t *testing.T) {
+		fi, err := m.GetObjectMeta(ctx, "bucket", "a/b.txt")
+		assert.NoError(t, err)
+		assert.Equal(t, "a/b.txt", fi.Name())
+		assert.Equal(t, int64(11), fi.Size())
+		assert.Equal(t, now, fi.ModTime())
+		assert.False(t, fi.IsDir())
+		assert.Equal(t, `"5EB63BBBE01EEED093CB22BB8F5ACDC3"`, fi.(*OssObjectMeta).ETag())
+	})
+}
+
+func TestMemObjectManagerGetObjectPart(t *testing.T) {
+	m := NewMemObjectManager()
+	ctx := context.TODO()
+	_, _ = m.PutObject(ctx, "bucket", "obj", strings.NewReader("0123456789"))
+
+	t.Run("inclusive range", func(t *testing.T) {
+		r, clean, err := m.GetObjectPart(ctx, "bucket", "obj", 2, 5)
+		assert.NoError(t, err)
+		assert.Equal(t, "2345", readAllForTest(t, r, clean))
+	})
+
+	t.Run("end beyond size is truncated", func(t *testing.T) {
+		r, clean, err := m.GetObjectPart(ctx, "bucket", "obj", 7, 100)
+		assert.NoError(t, err)
+		assert.Equal(t, "789", readAllForTest(t, r, clean))
+	})
+
+	t.Run("negative end reads to the end", func(t *testing.T) {
+		r, clean, err := m.GetObjectPart(ctx, "bucket", "obj", 6, -1)
+		assert.NoError(t, err)
+		assert.Equal(t, "6789", readAllForTest(t, r, clean))
+	})
+
+	t.Run("start beyond size is invalid", func(t *testing.T) {
+		_, _, err := m.GetObjectPart(ctx, "bucket", "obj", 10, 20)
+		var serr *oss.ServiceError
+		assert.True(t, errors.As(err, &serr))
+		assert.Equal(t, "InvalidRange", serr.Code)
+	})
+
+	t.Run("start after end is out of range", func(t *testing.T) {
+		_, _, err := m.GetObjectPart(ctx, "bucket", "obj", 5, 2)
+		assert.Error(t, err)
+	})
+}
+
+func TestMemObjectManagerCopyDelete(t *testing.T) {
+	m := NewMemObjectManager()
+	ctx := context.TODO()
+	_, _ = m.PutObject(ctx, "bucket", "src", strings.NewReader("data"))
+
+	t.Run("copy object", func(t *testing.T) {
+		assert.NoError(t, m.CopyObject(ctx, "bucket", "src", "dst"))
+		r, clean, err := m.GetObject(ctx, "bucket", "dst")
+		assert.NoError(t, err)
+		assert.Equal(t, "data", readAllForTest(t, r, clean))
+	})
+
+	t.Run("copy missing object fails", func(t *testing.T) {
+		assert.Error(t, m.CopyObject(ctx, "bucket", "missing", "dst2"))
+	})
+
+	t.Run("delete object", func(t *testing.T) {
+		assert.NoError(t, m.DeleteObject(ctx, "bucket", "src"))
+		existed, _ := m.IsObjectExist(ctx, "bucket", "src")
+		assert.False(t, existed)
+	})
+
+	t.Run("delete missing object succeeds", func(t *testing.T) {
+		assert.NoError(t, m.DeleteObject(ctx, "bucket", "src"))
+	})
+
+	t.Run("delete objects", func(t *testing.T) {
+		assert.NoError(t, m.DeleteObjects(ctx, "bucket", []string{"dst", "missing"}))
+		existed, _ := m.IsObjectExist(ctx, "bucket", "dst")
+		assert.False(t, existed)
+	})
+}
+
+func TestMemObjectManagerHierarchicalNamespace(t *testing.T) {
+	ctx := context.TODO()
+
+	t.Run("not supported without hierarchical namespace", func(t *testing.T) {
+		m := NewMemObjectManager()
+		_, _ = m.PutObject(ctx, "bucket", "a", strings.NewReader("data"))
+		var se *oss.ServiceError
+		assert.ErrorAs(t, m.RenameObject(ctx, "bucket", "a", "b"), &se)
+		assert.Equal(t, http.StatusBadRequest, se.StatusCode)
+		assert.ErrorAs(t, m.CreateDirectory(ctx, "bucket", "dir"), &se)
+	})
+
+	m := NewMemObjectManager()
+	m.HierarchicalNamespace = true
+
+	t.Run("create directory", func(t *testing.T) {
+		assert.NoError(t, m.CreateDirectory(ctx, "bucket", "dir"))
+		fi, err := m.GetObjectMeta(ctx, "bucket", "dir")
+		assert.NoError(t, err)
+		assert.True(t, fi.IsDir())
+		assert.Equal(t, "dir/", fi.Name())
+	})
+
+	t.Run("rename file", func(t *testing.T) {
+		_, _ = m.PutObject(ctx, "bucket", "dir/a.txt", strings.NewReader("a"))
+		assert.NoError(t, m.RenameObject(ctx, "bucket", "dir/a.txt", "dir/b.txt"))
+		existed, _ := m.IsObjectExist(ctx, "bucket", "dir/a.txt")
+		assert.False(t, existed)
+		r, clean, err := m.GetObject(ctx, "bucket", "dir/b.txt")
+		assert.NoError(t, err)
+		assert.Equal(t, "a", readAllForTest(t, r, clean))
+	})
+
+	t.Run("rename directory", func(t *testing.T) {
+		assert.NoError(t, m.RenameObject(ctx, "bucket", "dir", "moved"))
+		_, err := m.GetObjectMeta(ctx, "bucket", "dir")
+		assert.Error(t, err)
+		fi, err := m.GetObjectMeta(ctx, "bucket", "moved")
+		assert.NoError(t, err)
+		assert.True(t, fi.IsDir())
+		existed, _ := m.IsObjectExist(ctx, "bucket", "moved/b.txt")
+		assert.True(t, existed)
+	})
+
+	t.Run("rename missing object fails", func(t *testing.T) {
+		var se *oss.ServiceError
+		assert.ErrorAs(t, m.RenameObject(ctx, "bucket", "missing", "other"), &se)
+		assert.Equal(t, "NoSuchKey", se.Code)
+	})
+}
+
+func TestMemObjectManagerList(t *testing.T) {
+	m := NewMemObjectManager()
+	ctx := context.TODO()
+	for _, k := range []string{"dir/", "dir/b.txt", "dir/a.txt", "dir/sub/c.txt", "dirx", "other.txt"} {
+		_, _ = m.PutObject(ctx, "bucket", k, strings.NewReader(k))
+	}
+
+	names := func(fis []os.FileInfo) []string {
+		s := make([]string, 0, len(fis))
+		for _, fi := range fis {
+			s = append(s, fi.Name())
+		}
+		return s
+	}
+
+	t.Run("list with delimiter", func(t *testing.T) {
+		fis, err := m.ListObjects(ctx, "bucket", "dir/", 0)
+		assert.NoError(t, err)
+		assert.Equal(t, []string{"dir/a.txt", "dir/b.txt", "dir/sub/"}, names(fis))
+		assert.False(t, fis[0].IsDir())
+		assert.True(t, fis[2].IsDir())
+		assert.True(t, fis[2].Mode().IsDir())
+	})
+
+	t.Run("list with count", func(t *testing.T) {
+		fis, err := m.ListObjects(ctx, "bucket", "dir/", 2)
+		assert.NoError(t, err)
+		assert.Equal(t, []string{"dir/a.txt", "dir/b.txt"}, names(fis))
+	})
+
+	t.Run("list in pages", func(t *testing.T) {
+		fis, token, err := m.ListObjectsPage(ctx, "bucket", "dir/", "", 2)
+		assert.NoError(t, err)
+		assert.Equal(t, []string{"dir/a.txt", "dir/b.txt"}, names(fis))
+		assert.Equal(t, "dir/b.txt", token)
+
+		fis, token, err = m.ListObjectsPage(ctx, "bucket", "dir/", token, 2)
+		assert.NoError(t, err)
+		assert.Equal(t, []string{"dir/sub/"}, names(fis))
+		assert.Empty(t, token)
+
+		fis, token, err = m.ListObjectsPage(ctx, "bucket", "dir/", "dir/a.txt", 0)
+		assert.NoError(t, err)
+		assert.Equal(t, []string{"dir/b.txt", "dir/sub/"}, names(fis))
+		assert.Empty(t, token)
+	})
+
+	t.Run("list all objects", func(t *testing.T) {
+		fis, err := m.ListAllObjects(ctx, "bucket", "dir")
+		assert.NoError(t, err)
+		assert.Equal(t, []string{"dir/", "dir/a.txt", "dir/b.txt", "dir/sub/c.txt", "dirx"}, names(fis))
+	})
+
+	t.Run("iterate objects", func(t *testing.T) {
+		var fis []os.FileInfo
+		for fi, err := range m.IterObjects(ctx, "bucket", "dir/", false) {
+			assert.NoError(t, err)
+			fis = append(fis, fi)
+		}
+		assert.Equal(t, []string{"dir/a.txt", "dir/b.txt", "dir/sub/"}, names(fis))
+
+		fis = nil
+		for fi, err := range m.IterObjects(ctx, "bucket", "dir", true) {
+			assert.NoError(t, err)
+			fis = append(fis, fi)
+		}
+		assert.Equal(t, []string{"dir/", "dir/a.txt", "dir/b.txt", "dir/sub/c.txt", "dirx"}, names(fis))
+	})
+
+	t.Run("list missing bucket", func(t *testing.T) {
+		fis, err := m.ListAllObjects(ctx, "missing", "")
+		assert.NoError(t, err)
+		assert.Empty(t, fis)
+	})
+
+	t.Run("list with custom delimiter", func(t *testing.T) {
+		m := NewMemObjectManager()
+		m.Delimiter = ":"
+		for _, k := range []string{"dir:a.txt", "dir:sub:", "dir:sub:b.txt", "dir:c/d.txt"} {
+			_, _ = m.PutObject(ctx, "bucket", k, strings.NewReader(k))
+		}
+		fis, err := m.ListObjects(ctx, "bucket", "dir:", 0)
+		assert.NoError(t, err)
+		assert.Equal(t, []string{"dir:a.txt", "dir:c/d.txt", "dir:sub:"}, names(fis))
+		assert.False(t, fis[1].IsDir())
+		assert.True(t, fis[2].IsDir())
+
+		fi, err := m.GetObjectMeta(ctx, "bucket", "dir:sub:")
+		assert.NoError(t, err)
+		assert.True(t, fi.IsDir())
+	})
+}
+
+func TestMemObjectManagerMultipartUpload(t *testing.T) {
+	m := NewMemObjectManager()
+	ctx := context.TODO()
+
+	t.Run("complete upload in order of part number", func(t *testing.T) {
+		uploadId, err := m.InitiateMultipartUpload(ctx, "bucket", "large.txt")
+		assert.NoError(t, err)
+		p2, err := m.UploadPart(ctx, "bucket", "large.txt", uploadId, 2, strings.NewReader("world"))
+		assert.NoError(t, err)
+		p1, err := m.UploadPart(ctx, "bucket", "large.txt", uploadId, 1, strings.NewReader("hello "))
+		assert.NoError(t, err)
+		assert.Equal(t, int32(1), p1.PartNumber)
+		assert.Equal(t, 1, m.MultipartUploads())
+
+		assert.NoError(t, m.CompleteMultipartUpload(ctx, "bucket", "large.txt", uploadId, []UploadedPart{p2, p1}))
+		assert.Equal(t, 0, m.MultipartUploads())
+		r, clean, err := m.GetObject(ctx, "bucket", "large.txt")
+		assert.NoError(t, err)
+		assert.Equal(t, "hello world", readAllForTest(t, r, clean))
+	})
+
+	t.Run("complete with mismatched etag fails", func(t *testing.T) {
+		uploadId, _ := m.InitiateMultipartUpload(ctx, "bucket", "bad.txt")
+		_, err := m.UploadPart(ctx, "bucket", "bad.txt", uploadId, 1, strings.NewReader("data"))
+		assert.NoError(t, err)
+		err = m.CompleteMultipartUpload(ctx, "bucket", "bad.txt", uploadId, []UploadedPart{{PartNumber: 1, ETag: `"0"`}})
+		var serr *oss.ServiceError
+		assert.True(t, errors.As(err, &serr))
+		assert.Equal(t, "InvalidPart", serr.Code)
+		assert.NoError(t, m.AbortMultipartUpload(ctx, "bucket", "bad.txt", uploadId))
+	})
+
+	t.Run("aborted upload no longer exists", func(t *testing.T) {
+		uploadId, _ := m.InitiateMultipartUpload(ctx, "bucket", "aborted.txt")
+		assert.NoError(t, m.AbortMultipartUpload(ctx, "bucket", "aborted.txt", uploadId))
+		_, err := m.UploadPart(ctx, "bucket", "aborted.txt", uploadId, 1, strings.NewReader("data"))
+		var serr *oss.ServiceError
+		assert.True(t, errors.As(err, &serr))
+		assert.Equal(t, "NoSuchUpload", serr.Code)
+		assert.Equal(t, http.StatusNotFound, serr.StatusCode)
+
+		existed, _ := m.IsObjectExist(ctx, "bucket", "aborted.txt")
+		assert.False(t, existed)
+	})
+}
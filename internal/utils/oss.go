@@ -2,10 +2,15 @@ package utils
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"io"
 	"io/fs"
+	"iter"
+	"net/http"
+	"net/url"
 	"os"
+	"slices"
 	"strings"
 	"time"
 
@@ -18,9 +23,31 @@ var (
 	ossDefaultFileMode fs.FileMode = 0o755
 )
 
+const (
+	ossMaxListKeys   = 1000        // the maximum number of entries of a listed page.
+	ossMaxDeleteKeys = 1000        // the maximum number of objects deleted by a request.
+	ossDirectoryType = "Directory" // the object type of directories of hierarchical namespace.
+)
+
 type OssObjectManager struct {
 	ObjectManager
 	Client *oss.Client
+
+	// The size above which objects are copied in parts of copyPartSize bytes,
+	// the defaults of oss.Copier are used if they are zero.
+	copyThreshold int64
+	copyPartSize  int64
+
+	// Delimiter is the separator of directories in keys, which delimits
+	// listings and ends the names of directories, it's "/" if empty.
+	Delimiter string
+}
+
+func (m *OssObjectManager) delimiter() string {
+	if m.Delimiter == "" {
+		return ossDirSeparator
+	}
+	return m.Delimiter
 }
 
 func (m *OssObjectManager) GetObject(ctx context.Context, bucket, name string) (io.Reader, CleanUp, error) {
@@ -29,27 +56,37 @@ func (m *OssObjectManager) GetObject(ctx context.Context, bucket, name string) (
 		Key:    oss.Ptr(name),
 	}
 	res, err := m.Client.GetObject(ctx, req)
+	if err != nil {
+		return nil, nil, err
+	}
 	cleanUp := func() {
 		res.Body.Close()
 	}
-	return res.Body, cleanUp, err
+	return res.Body, cleanUp, nil
 }
 
 func (m *OssObjectManager) GetObjectPart(ctx context.Context, bucket, name string, start, end int64) (io.Reader, CleanUp, error) {
-	if start > end {
+	if end >= 0 && start > end {
 		return nil, nil, afero.ErrOutOfRange
 	}
+	rng := fmt.Sprintf("bytes=%v-%v", start, end)
+	if end < 0 {
+		rng = fmt.Sprintf("bytes=%v-", start)
+	}
 	req := &oss.GetObjectRequest{
 		Bucket:        oss.Ptr(bucket),
 		Key:           oss.Ptr(name),
-		Range:         oss.Ptr(fmt.Sprintf("bytes=%v-%v", start, end)),
+		Range:         oss.Ptr(rng),
 		RangeBehavior: oss.Ptr("standard"),
 	}
 	res, err := m.Client.GetObject(ctx, req)
+	if err != nil {
+		return nil, nil, err
+	}
 	cleanUp := func() {
 		res.Body.Close()
 	}
-	return res.Body, cleanUp, err
+	return res.Body, cleanUp, nil
 }
 
 func (m *OssObjectManager) DeleteObject(ctx context.Context, bucket, name string) error {
@@ -61,6 +98,41 @@ func (m *OssObjectManager) DeleteObject(ctx context.Context, bucket, name string
 	return err
 }
 
+// DeleteObjects deletes the objects by DeleteMultipleObjects, all the objects
+// of a failed request are reported, as well as those not listed as deleted in
+// the response.
+func (m *OssObjectManager) DeleteObjects(ctx context.Context, bucket string, names []string) error {
+	var errs []error
+	for batch := range slices.Chunk(names, ossMaxDeleteKeys) {
+		objects := make([]oss.DeleteObject, 0, len(batch))
+		for _, name := range batch {
+			objects = append(objects, oss.DeleteObject{Key: oss.Ptr(name)})
+		}
+		req := &oss.DeleteMultipleObjectsRequest{
+			Bucket:  oss.Ptr(bucket),
+			Objects: objects,
+		}
+		res, err := m.Client.DeleteMultipleObjects(ctx, req)
+		if err != nil {
+			for _, name := range batch {
+				errs = append(errs, &DeleteError{Name: name, Err: err})
+			}
+			continue
+		}
+
+		deleted := make(map[string]bool, len(res.DeletedObjects))
+		for _, obj := range res.DeletedObjects {
+			deleted[oss.ToString(obj.Key)] = true
+		}
+		for _, name := range batch {
+			if !deleted[name] {
+				errs = append(errs, &DeleteError{Name: name, Err: ErrNotDeleted})
+			}
+		}
+	}
+	return errors.Join(errs...)
+}
+
 func (m *OssObjectManager) IsObjectExist(ctx context.Context, bucket, name string) (bool, error) {
 	return m.Client.IsObjectExist(ctx, bucket, name)
 }
@@ -78,18 +150,84 @@ func (m *OssObjectManager) PutObject(ctx context.Context, bucket, name string, r
 	return true, nil
 }
 
+// CopyObject copies the object by oss.Copier, objects larger than its
+// multipart copy threshold are copied in parts by UploadPartCopy, which works
+// beyond the 5 GB limit of CopyObject. The metadata and storage class of the
+// source object are kept.
 func (m *OssObjectManager) CopyObject(ctx context.Context, bucket, srcName, targetName string) error {
+	head, err := m.Client.HeadObject(ctx, &oss.HeadObjectRequest{
+		Bucket: oss.Ptr(bucket),
+		Key:    oss.Ptr(srcName),
+	})
+	if err != nil {
+		return err
+	}
+
 	req := &oss.CopyObjectRequest{
 		Bucket:       oss.Ptr(bucket),
-		Key:          oss.Ptr(srcName),
-		SourceKey:    oss.Ptr(targetName),
+		Key:          oss.Ptr(targetName),
+		SourceKey:    oss.Ptr(srcName),
 		SourceBucket: oss.Ptr(bucket),
-		StorageClass: oss.StorageClassStandard,
+		StorageClass: oss.StorageClassType(oss.ToString(head.StorageClass)),
 	}
-	_, err := m.Client.CopyObject(ctx, req)
+	copier := m.Client.NewCopier(func(o *oss.CopierOptions) {
+		if m.copyThreshold > 0 {
+			o.MultipartCopyThreshold = m.copyThreshold
+		}
+		if m.copyPartSize > 0 {
+			o.PartSize = m.copyPartSize
+		}
+	})
+	_, err = copier.Copy(ctx, req, func(o *oss.CopierOptions) {
+		o.MetadataProperties = head
+	})
 	return err
 }
 
+// RenameObject renames the object or directory by the Rename operation of
+// hierarchical namespace.
+func (m *OssObjectManager) RenameObject(ctx context.Context, bucket, srcName, targetName string) error {
+	segments := strings.Split(srcName, ossDirSeparator)
+	for i, seg := range segments {
+		segments[i] = url.PathEscape(seg)
+	}
+	return m.invoke(ctx, &oss.OperationInput{
+		OpName:     "Rename",
+		Method:     http.MethodPost,
+		Bucket:     oss.Ptr(bucket),
+		Key:        oss.Ptr(targetName),
+		Parameters: map[string]string{"x-oss-rename": ""},
+		Headers: map[string]string{
+			"x-oss-rename-source": "/" + bucket + "/" + strings.Join(segments, ossDirSeparator),
+		},
+	})
+}
+
+// CreateDirectory creates the directory by the CreateDirectory operation of
+// hierarchical namespace.
+func (m *OssObjectManager) CreateDirectory(ctx context.Context, bucket, name string) error {
+	return m.invoke(ctx, &oss.OperationInput{
+		OpName:     "CreateDirectory",
+		Method:     http.MethodPost,
+		Bucket:     oss.Ptr(bucket),
+		Key:        oss.Ptr(name),
+		Parameters: map[string]string{"x-oss-dir": ""},
+	})
+}
+
+// invoke invokes an operation not wrapped by the SDK and discards its output.
+func (m *OssObjectManager) invoke(ctx context.Context, input *oss.OperationInput) error {
+	output, err := m.Client.InvokeOperation(ctx, input)
+	if err != nil {
+		return err
+	}
+	if output.Body != nil {
+		_, _ = io.Copy(io.Discard, output.Body)
+		output.Body.Close()
+	}
+	return nil
+}
+
 func (m *OssObjectManager) GetObjectMeta(ctx context.Context, bucket, name string) (os.FileInfo, error) {
 	req := &oss.HeadObjectRequest{
 		Bucket: oss.Ptr(bucket),
@@ -100,46 +238,93 @@ func (m *OssObjectManager) GetObjectMeta(ctx context.Context, bucket, name strin
 	if err != nil {
 		return nil, err
 	}
+	// A directory of hierarchical namespace is named without the separator.
+	if oss.ToString(res.ObjectType) == ossDirectoryType && !strings.HasSuffix(name, ossDirSeparator) {
+		name += ossDirSeparator
+	}
 	return &OssObjectMeta{
 		name:           name,
 		size:           res.ContentLength,
-		lastModifiedAt: *res.LastModified,
+		lastModifiedAt: oss.ToTime(res.LastModified),
+		etag:           oss.ToString(res.ETag),
+		sep:            m.delimiter(),
 	}, nil
 }
 
+// ListObjects lists the objects and directories directly under prefix,
+// directories are the common prefixes of objects, ending with the separator.
+// The directory marker object of the prefix itself is not listed.
 func (m *OssObjectManager) ListObjects(ctx context.Context, bucket, prefix string, count int) ([]os.FileInfo, error) {
-	req := &oss.ListObjectsV2Request{
-		Bucket:    oss.Ptr(bucket),
-		Delimiter: oss.Ptr(ossDirSeparator),
-		Prefix:    oss.Ptr(prefix),
-	}
-	p := m.Client.NewListObjectsV2Paginator(req)
+	s, _, err := m.ListObjectsPage(ctx, bucket, prefix, "", count)
+	return s, err
+}
 
+// ListObjectsPage lists at most count (all if count <= 0) entries as
+// ListObjects does, starting after the entries of the previous page whose
+// continuation token is token, empty for the first page. The returned token is
+// empty if there are no more entries.
+func (m *OssObjectManager) ListObjectsPage(ctx context.Context, bucket, prefix, token string, count int) ([]os.FileInfo, string, error) {
 	s := make([]os.FileInfo, 0)
 
-	var i int
-
-loop:
-	for p.HasNext() {
-		page, err := p.NextPage(ctx)
+	for {
+		req := &oss.ListObjectsV2Request{
+			Bucket:    oss.Ptr(bucket),
+			Delimiter: oss.Ptr(m.delimiter()),
+			Prefix:    oss.Ptr(prefix),
+		}
+		if token != "" {
+			req.ContinuationToken = oss.Ptr(token)
+		}
+		if count > 0 {
+			req.MaxKeys = int32(min(count-len(s), ossMaxListKeys))
+		}
+		page, err := m.Client.ListObjectsV2(ctx, req)
 		if err != nil {
-			return nil, err
+			return nil, "", err
 		}
 
-		for _, obj := range page.Contents {
-			i++
-			if i == count {
-				break loop
-			}
-			s = append(s, &OssObjectMeta{
-				name:           oss.ToString(obj.Key),
-				size:           obj.Size,
-				lastModifiedAt: oss.ToTime(obj.LastModified),
-			})
+		s = append(s, listEntries(prefix, m.delimiter(), page.Contents, page.CommonPrefixes)...)
+
+		token = ""
+		if page.IsTruncated {
+			token = oss.ToString(page.NextContinuationToken)
+		}
+		// A page may be short of the directory marker, the next one fills it.
+		if token == "" || (count > 0 && len(s) >= count) {
+			return s, token, nil
 		}
 	}
+}
 
-	return s, nil
+// listEntries returns the objects and common prefixes of a listed page in
+// lexicographical order as OSS lists them, the directory marker of prefix is
+// skipped. Directories are the names ending with sep.
+func listEntries(prefix, sep string, contents []oss.ObjectProperties, prefixes []oss.CommonPrefix) []os.FileInfo {
+	entries := make([]*OssObjectMeta, 0, len(contents)+len(prefixes))
+	for _, obj := range contents {
+		if oss.ToString(obj.Key) == prefix {
+			continue
+		}
+		entries = append(entries, &OssObjectMeta{
+			name:           oss.ToString(obj.Key),
+			size:           obj.Size,
+			lastModifiedAt: oss.ToTime(obj.LastModified),
+			etag:           oss.ToString(obj.ETag),
+			sep:            sep,
+		})
+	}
+	for _, cp := range prefixes {
+		entries = append(entries, &OssObjectMeta{name: oss.ToString(cp.Prefix), sep: sep})
+	}
+	slices.SortFunc(entries, func(a, b *OssObjectMeta) int {
+		return strings.Compare(a.name, b.name)
+	})
+
+	s := make([]os.FileInfo, 0, len(entries))
+	for _, entry := range entries {
+		s = append(s, entry)
+	}
+	return s
 }
 
 func (m *OssObjectManager) ListAllObjects(ctx context.Context, bucket, prefix string) ([]os.FileInfo, error) {
@@ -162,6 +347,8 @@ func (m *OssObjectManager) ListAllObjects(ctx context.Context, bucket, prefix st
 				name:           oss.ToString(obj.Key),
 				size:           obj.Size,
 				lastModifiedAt: oss.ToTime(obj.LastModified),
+				etag:           oss.ToString(obj.ETag),
+				sep:            m.delimiter(),
 			})
 		}
 	}
@@ -169,11 +356,101 @@ func (m *OssObjectManager) ListAllObjects(ctx context.Context, bucket, prefix st
 	return s, nil
 }
 
+// IterObjects iterates the objects having prefix, a page of ListObjectsV2 is
+// requested only when the entries of the previous page are consumed.
+func (m *OssObjectManager) IterObjects(ctx context.Context, bucket, prefix string, recursive bool) iter.Seq2[os.FileInfo, error] {
+	return func(yield func(os.FileInfo, error) bool) {
+		req := &oss.ListObjectsV2Request{
+			Bucket: oss.Ptr(bucket),
+			Prefix: oss.Ptr(prefix),
+		}
+		// A recursive listing includes the directory marker of prefix as
+		// ListAllObjects does.
+		marker := ""
+		if !recursive {
+			req.Delimiter = oss.Ptr(m.delimiter())
+			marker = prefix
+		}
+		p := m.Client.NewListObjectsV2Paginator(req)
+
+		for p.HasNext() {
+			page, err := p.NextPage(ctx)
+			if err != nil {
+				yield(nil, err)
+				return
+			}
+			for _, fi := range listEntries(marker, m.delimiter(), page.Contents, page.CommonPrefixes) {
+				if !yield(fi, nil) {
+					return
+				}
+			}
+		}
+	}
+}
+
+func (m *OssObjectManager) InitiateMultipartUpload(ctx context.Context, bucket, name string) (string, error) {
+	req := &oss.InitiateMultipartUploadRequest{
+		Bucket: oss.Ptr(bucket),
+		Key:    oss.Ptr(name),
+	}
+	res, err := m.Client.InitiateMultipartUpload(ctx, req)
+	if err != nil {
+		return "", err
+	}
+	return oss.ToString(res.UploadId), nil
+}
+
+func (m *OssObjectManager) UploadPart(ctx context.Context, bucket, name, uploadId string, partNumber int32, reader io.Reader) (UploadedPart, error) {
+	req := &oss.UploadPartRequest{
+		Bucket:     oss.Ptr(bucket),
+		Key:        oss.Ptr(name),
+		UploadId:   oss.Ptr(uploadId),
+		PartNumber: partNumber,
+		Body:       reader,
+	}
+	res, err := m.Client.UploadPart(ctx, req)
+	if err != nil {
+		return UploadedPart{}, err
+	}
+	return UploadedPart{PartNumber: partNumber, ETag: oss.ToString(res.ETag)}, nil
+}
+
+func (m *OssObjectManager) CompleteMultipartUpload(ctx context.Context, bucket, name, uploadId string, parts []UploadedPart) error {
+	uploadParts := make([]oss.UploadPart, 0, len(parts))
+	for _, p := range parts {
+		uploadParts = append(uploadParts, oss.UploadPart{PartNumber: p.PartNumber, ETag: oss.Ptr(p.ETag)})
+	}
+	req := &oss.CompleteMultipartUploadRequest{
+		Bucket:   oss.Ptr(bucket),
+		Key:      oss.Ptr(name),
+		UploadId: oss.Ptr(uploadId),
+		CompleteMultipartUpload: &oss.CompleteMultipartUpload{
+			Parts: uploadParts,
+		},
+	}
+	_, err := m.Client.CompleteMultipartUpload(ctx, req)
+	return err
+}
+
+func (m *OssObjectManager) AbortMultipartUpload(ctx context.Context, bucket, name, uploadId string) error {
+	req := &oss.AbortMultipartUploadRequest{
+		Bucket:   oss.Ptr(bucket),
+		Key:      oss.Ptr(name),
+		UploadId: oss.Ptr(uploadId),
+	}
+	_, err := m.Client.AbortMultipartUpload(ctx, req)
+	return err
+}
+
 type OssObjectMeta struct {
 	os.FileInfo
 	name           string
 	size           int64
 	lastModifiedAt time.Time
+	etag           string
+
+	// The separator ending the names of directories, "/" if empty.
+	sep string
 }
 
 func NewOssObjectMeta(name string, size int64, updatedAt time.Time) *OssObjectMeta {
@@ -184,10 +461,23 @@ func NewOssObjectMeta(name string, size int64, updatedAt time.Time) *OssObjectMe
 	}
 }
 
+// NewOssDirMeta returns the meta of the directory name, which ends with the
+// separator sep.
+func NewOssDirMeta(name, sep string) *OssObjectMeta {
+	return &OssObjectMeta{name: name, sep: sep}
+}
+
 func (objMeta *OssObjectMeta) isDir() bool {
+	if objMeta.sep != "" {
+		return strings.HasSuffix(objMeta.name, objMeta.sep)
+	}
 	return strings.HasSuffix(objMeta.name, ossDirSeparator)
 }
 
+func (objMeta *OssObjectMeta) IsDir() bool {
+	return objMeta.isDir()
+}
+
 func (objMeta *OssObjectMeta) ModTime() time.Time {
 	return objMeta.lastModifiedAt
 }
@@ -207,6 +497,12 @@ func (objMeta *OssObjectMeta) Size() int64 {
 	return objMeta.size
 }
 
+// ETag returns the entity tag of the object, which changes whenever the object
+// content changes. It's empty if unknown.
+func (objMeta *OssObjectMeta) ETag() string {
+	return objMeta.etag
+}
+
 func (objMeta *OssObjectMeta) Sys() any {
 	return nil
 }
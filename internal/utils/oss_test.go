@@ -0,0 +1,412 @@
+package utils
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/aliyun/alibabacloud-oss-go-sdk-v2/oss"
+	"github.com/aliyun/alibabacloud-oss-go-sdk-v2/oss/credentials"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/messikiller/afero-oss/osstest"
+)
+
+func getEmulatedManager(t *testing.T) *OssObjectManager {
+	_, m := getEmulatedServer(t)
+	return m
+}
+
+func getEmulatedServer(t *testing.T) (*osstest.Server, *OssObjectManager) {
+	srv := osstest.NewServer("bucket")
+	t.Cleanup(srv.Close)
+	cfg := oss.LoadDefaultConfig().
+		WithCredentialsProvider(credentials.NewStaticCredentialsProvider("ak", "sk")).
+		WithRegion("cn-hangzhou").
+		WithEndpoint(srv.URL).
+		WithUsePathStyle(true)
+	return srv, &OssObjectManager{Client: oss.NewClient(cfg)}
+}
+
+func TestOssObjectManagerObjects(t *testing.T) {
+	m := getEmulatedManager(t)
+	ctx := context.TODO()
+
+	t.Run("put and get object", func(t *testing.T) {
+		ok, err := m.PutObject(ctx, "bucket", "a/b c.txt", strings.NewReader("hello world"))
+		assert.NoError(t, err)
+		assert.True(t, ok)
+
+		r, clean, err := m.GetObject(ctx, "bucket", "a/b c.txt")
+		assert.NoError(t, err)
+		assert.Equal(t, "hello world", readAllForTest(t, r, clean))
+	})
+
+	t.Run("get object part", func(t *testing.T) {
+		r, clean, err := m.GetObjectPart(ctx, "bucket", "a/b c.txt", 6, 10)
+		assert.NoError(t, err)
+		assert.Equal(t, "world", readAllForTest(t, r, clean))
+	})
+
+	t.Run("get object part to the end", func(t *testing.T) {
+		r, clean, err := m.GetObjectPart(ctx, "bucket", "a/b c.txt", 6, -1)
+		assert.NoError(t, err)
+		assert.Equal(t, "world", readAllForTest(t, r, clean))
+	})
+
+	t.Run("get object part beyond size", func(t *testing.T) {
+		_, _, err := m.GetObjectPart(ctx, "bucket", "a/b c.txt", 11, 20)
+		var serr *oss.ServiceError
+		assert.True(t, errors.As(err, &serr))
+		assert.Equal(t, "InvalidRange", serr.Code)
+	})
+
+	t.Run("get missing object", func(t *testing.T) {
+		_, _, err := m.GetObject(ctx, "bucket", "missing")
+		var serr *oss.ServiceError
+		assert.True(t, errors.As(err, &serr))
+		assert.Equal(t, "NoSuchKey", serr.Code)
+		assert.NotEmpty(t, serr.RequestID)
+	})
+
+	t.Run("object meta and existence", func(t *testing.T) {
+		fi, err := m.GetObjectMeta(ctx, "bucket", "a/b c.txt")
+		assert.NoError(t, err)
+		assert.Equal(t, "a/b c.txt", fi.Name())
+		assert.Equal(t, int64(11), fi.Size())
+		assert.False(t, fi.ModTime().IsZero())
+		assert.NotEmpty(t, fi.(*OssObjectMeta).ETag())
+
+		existed, err := m.IsObjectExist(ctx, "bucket", "a/b c.txt")
+		assert.NoError(t, err)
+		assert.True(t, existed)
+
+		existed, err = m.IsObjectExist(ctx, "bucket", "missing")
+		assert.NoError(t, err)
+		assert.False(t, existed)
+	})
+
+	t.Run("meta of missing object", func(t *testing.T) {
+		_, err := m.GetObjectMeta(ctx, "bucket", "missing")
+		var serr *oss.ServiceError
+		assert.True(t, errors.As(err, &serr))
+		assert.Equal(t, "NoSuchKey", serr.Code)
+	})
+
+	t.Run("copy and delete object", func(t *testing.T) {
+		assert.NoError(t, m.CopyObject(ctx, "bucket", "a/b c.txt", "copied.txt"))
+		r, clean, err := m.GetObject(ctx, "bucket", "copied.txt")
+		assert.NoError(t, err)
+		assert.Equal(t, "hello world", readAllForTest(t, r, clean))
+
+		assert.NoError(t, m.DeleteObject(ctx, "bucket", "copied.txt"))
+		existed, _ := m.IsObjectExist(ctx, "bucket", "copied.txt")
+		assert.False(t, existed)
+	})
+
+	t.Run("missing bucket", func(t *testing.T) {
+		_, err := m.PutObject(ctx, "missing-bucket", "a.txt", strings.NewReader(""))
+		var serr *oss.ServiceError
+		assert.True(t, errors.As(err, &serr))
+		assert.Equal(t, "NoSuchBucket", serr.Code)
+	})
+}
+
+func TestOssObjectManagerCopyObject(t *testing.T) {
+	m := getEmulatedManager(t)
+	ctx := context.TODO()
+
+	data := strings.Repeat("0123456789", 300)
+	_, err := m.Client.PutObject(ctx, &oss.PutObjectRequest{
+		Bucket:       oss.Ptr("bucket"),
+		Key:          oss.Ptr("src.txt"),
+		Body:         strings.NewReader(data),
+		ContentType:  oss.Ptr("text/plain"),
+		Metadata:     map[string]string{"owner": "alice"},
+		StorageClass: oss.StorageClassIA,
+	})
+	assert.NoError(t, err)
+
+	tests := []struct {
+		name      string
+		threshold int64
+		multipart bool
+	}{
+		{name: "single copy"},
+		{name: "multipart copy", threshold: 1024, multipart: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m.copyThreshold, m.copyPartSize = tt.threshold, 512
+			assert.NoError(t, m.CopyObject(ctx, "bucket", "src.txt", "dst.txt"))
+
+			head, err := m.Client.HeadObject(ctx, &oss.HeadObjectRequest{
+				Bucket: oss.Ptr("bucket"),
+				Key:    oss.Ptr("dst.txt"),
+			})
+			assert.NoError(t, err)
+			assert.Equal(t, int64(len(data)), head.ContentLength)
+			assert.Equal(t, "text/plain", oss.ToString(head.ContentType))
+			assert.Equal(t, "alice", head.Metadata["owner"])
+			assert.Equal(t, "IA", oss.ToString(head.StorageClass))
+			// a multipart object has an ETag like "<md5>-<parts>".
+			assert.Equal(t, tt.multipart, strings.HasSuffix(oss.ToString(head.ETag), `-6"`))
+
+			r, clean, err := m.GetObject(ctx, "bucket", "dst.txt")
+			assert.NoError(t, err)
+			assert.Equal(t, data, readAllForTest(t, r, clean))
+		})
+	}
+
+	t.Run("copy missing object", func(t *testing.T) {
+		err := m.CopyObject(ctx, "bucket", "missing", "dst.txt")
+		var serr *oss.ServiceError
+		assert.True(t, errors.As(err, &serr))
+		assert.Equal(t, "NoSuchKey", serr.Code)
+	})
+}
+
+func TestOssObjectManagerDeleteObjects(t *testing.T) {
+	m := getEmulatedManager(t)
+	ctx := context.TODO()
+
+	// more keys than a single request deletes.
+	names := make([]string, 0, 1005)
+	for i := range 1005 {
+		names = append(names, fmt.Sprintf("dir/f%04d &?.txt", i))
+	}
+	for _, name := range names[:10] {
+		_, err := m.PutObject(ctx, "bucket", name, strings.NewReader("x"))
+		assert.NoError(t, err)
+	}
+	_, _ = m.PutObject(ctx, "bucket", "kept", strings.NewReader("x"))
+
+	t.Run("delete in batches", func(t *testing.T) {
+		assert.NoError(t, m.DeleteObjects(ctx, "bucket", names))
+		fis, err := m.ListAllObjects(ctx, "bucket", "")
+		assert.NoError(t, err)
+		assert.Len(t, fis, 1)
+		assert.Equal(t, "kept", fis[0].Name())
+	})
+
+	t.Run("nothing to delete", func(t *testing.T) {
+		assert.NoError(t, m.DeleteObjects(ctx, "bucket", nil))
+	})
+
+	t.Run("failures are reported per object", func(t *testing.T) {
+		err := m.DeleteObjects(ctx, "missing-bucket", []string{"a", "b"})
+		var derr *DeleteError
+		assert.True(t, errors.As(err, &derr))
+		assert.Equal(t, "a", derr.Name)
+		assert.Contains(t, err.Error(), "delete b: ")
+		var serr *oss.ServiceError
+		assert.True(t, errors.As(err, &serr))
+		assert.Equal(t, "NoSuchBucket", serr.Code)
+	})
+}
+
+func TestOssObjectManagerList(t *testing.T) {
+	m := getEmulatedManager(t)
+	ctx := context.TODO()
+
+	// more keys than a single page of ListObjectsV2 holds.
+	for i := range 120 {
+		_, err := m.PutObject(ctx, "bucket", fmt.Sprintf("dir/f%03d", i), strings.NewReader("x"))
+		assert.NoError(t, err)
+	}
+	_, _ = m.PutObject(ctx, "bucket", "dir/sub/nested", strings.NewReader("x"))
+	_, _ = m.PutObject(ctx, "bucket", "other", strings.NewReader("x"))
+
+	t.Run("list all objects across pages", func(t *testing.T) {
+		fis, err := m.ListAllObjects(ctx, "bucket", "dir/")
+		assert.NoError(t, err)
+		assert.Len(t, fis, 121)
+		assert.Equal(t, "dir/f000", fis[0].Name())
+		assert.Equal(t, "dir/sub/nested", fis[120].Name())
+	})
+
+	t.Run("list objects with delimiter", func(t *testing.T) {
+		fis, err := m.ListObjects(ctx, "bucket", "dir/", 0)
+		assert.NoError(t, err)
+		assert.Len(t, fis, 121)
+		for _, fi := range fis[:120] {
+			assert.False(t, fi.IsDir())
+			assert.NotEqual(t, "dir/sub/nested", fi.Name())
+		}
+		assert.Equal(t, "dir/sub/", fis[120].Name())
+		assert.True(t, fis[120].IsDir())
+	})
+
+	t.Run("list objects with count", func(t *testing.T) {
+		fis, err := m.ListObjects(ctx, "bucket", "dir/", 50)
+		assert.NoError(t, err)
+		assert.Len(t, fis, 50)
+		assert.Equal(t, "dir/f049", fis[49].Name())
+	})
+
+	t.Run("list objects in pages", func(t *testing.T) {
+		var names []string
+		token := ""
+		for pages := 1; ; pages++ {
+			fis, next, err := m.ListObjectsPage(ctx, "bucket", "dir/", token, 50)
+			assert.NoError(t, err)
+			for _, fi := range fis {
+				names = append(names, fi.Name())
+			}
+			if next == "" {
+				assert.Equal(t, 3, pages)
+				break
+			}
+			assert.Len(t, fis, 50)
+			token = next
+		}
+		assert.Len(t, names, 121)
+		assert.Equal(t, "dir/f050", names[50])
+		assert.Equal(t, "dir/sub/", names[120])
+	})
+
+	t.Run("iterate objects across pages", func(t *testing.T) {
+		collect := func(recursive bool) []string {
+			var names []string
+			for fi, err := range m.IterObjects(ctx, "bucket", "dir/", recursive) {
+				assert.NoError(t, err)
+				names = append(names, fi.Name())
+			}
+			return names
+		}
+
+		names := collect(true)
+		assert.Len(t, names, 121)
+		assert.Equal(t, "dir/sub/nested", names[120])
+
+		names = collect(false)
+		assert.Len(t, names, 121)
+		assert.Equal(t, "dir/sub/", names[120])
+
+		for fi, err := range m.IterObjects(ctx, "bucket", "dir/", true) {
+			assert.NoError(t, err)
+			assert.Equal(t, "dir/f000", fi.Name())
+			break
+		}
+	})
+
+	t.Run("iterate missing bucket", func(t *testing.T) {
+		var errs []error
+		for fi, err := range m.IterObjects(ctx, "missing-bucket", "", true) {
+			assert.Nil(t, fi)
+			errs = append(errs, err)
+		}
+		assert.Len(t, errs, 1)
+		var serr *oss.ServiceError
+		assert.True(t, errors.As(errs[0], &serr))
+		assert.Equal(t, "NoSuchBucket", serr.Code)
+	})
+
+	t.Run("list objects without directory marker", func(t *testing.T) {
+		_, err := m.PutObject(ctx, "bucket", "dir/sub/", strings.NewReader(""))
+		assert.NoError(t, err)
+		fis, err := m.ListObjects(ctx, "bucket", "dir/sub/", 0)
+		assert.NoError(t, err)
+		assert.Len(t, fis, 1)
+		assert.Equal(t, "dir/sub/nested", fis[0].Name())
+	})
+}
+
+func TestOssObjectManagerMultipartUpload(t *testing.T) {
+	m := getEmulatedManager(t)
+	ctx := context.TODO()
+
+	t.Run("upload and complete", func(t *testing.T) {
+		uploadId, err := m.InitiateMultipartUpload(ctx, "bucket", "dir/large file.txt")
+		assert.NoError(t, err)
+		assert.NotEmpty(t, uploadId)
+
+		var parts []UploadedPart
+		for i, s := range []string{"hello ", "multipart ", "upload"} {
+			part, err := m.UploadPart(ctx, "bucket", "dir/large file.txt", uploadId, int32(i+1), strings.NewReader(s))
+			assert.NoError(t, err)
+			assert.Equal(t, int32(i+1), part.PartNumber)
+			assert.NotEmpty(t, part.ETag)
+			parts = append(parts, part)
+		}
+		// parts may be completed in any order.
+		parts[0], parts[2] = parts[2], parts[0]
+		assert.NoError(t, m.CompleteMultipartUpload(ctx, "bucket", "dir/large file.txt", uploadId, parts))
+
+		r, clean, err := m.GetObject(ctx, "bucket", "dir/large file.txt")
+		assert.NoError(t, err)
+		assert.Equal(t, "hello multipart upload", readAllForTest(t, r, clean))
+	})
+
+	t.Run("abort upload", func(t *testing.T) {
+		uploadId, err := m.InitiateMultipartUpload(ctx, "bucket", "aborted.txt")
+		assert.NoError(t, err)
+		_, err = m.UploadPart(ctx, "bucket", "aborted.txt", uploadId, 1, strings.NewReader("data"))
+		assert.NoError(t, err)
+		assert.NoError(t, m.AbortMultipartUpload(ctx, "bucket", "aborted.txt", uploadId))
+
+		err = m.AbortMultipartUpload(ctx, "bucket", "aborted.txt", uploadId)
+		var serr *oss.ServiceError
+		assert.True(t, errors.As(err, &serr))
+		assert.Equal(t, "NoSuchUpload", serr.Code)
+
+		existed, _ := m.IsObjectExist(ctx, "bucket", "aborted.txt")
+		assert.False(t, existed)
+	})
+}
+
+func TestOssObjectManagerHierarchicalNamespace(t *testing.T) {
+	srv, m := getEmulatedServer(t)
+	ctx := context.TODO()
+
+	t.Run("not supported without hierarchical namespace", func(t *testing.T) {
+		_, _ = m.PutObject(ctx, "bucket", "a", strings.NewReader("data"))
+		var se *oss.ServiceError
+		assert.ErrorAs(t, m.RenameObject(ctx, "bucket", "a", "b"), &se)
+		assert.Equal(t, "OperationNotSupported", se.Code)
+	})
+
+	srv.EnableHierarchicalNamespace("hns")
+
+	t.Run("create directory", func(t *testing.T) {
+		assert.NoError(t, m.CreateDirectory(ctx, "hns", "dir"))
+		fi, err := m.GetObjectMeta(ctx, "hns", "dir")
+		assert.NoError(t, err)
+		assert.True(t, fi.IsDir())
+		assert.Equal(t, "dir/", fi.Name())
+	})
+
+	t.Run("rename file", func(t *testing.T) {
+		_, _ = m.PutObject(ctx, "hns", "dir/a b.txt", strings.NewReader("a"))
+		assert.NoError(t, m.RenameObject(ctx, "hns", "dir/a b.txt", "dir/b+c.txt"))
+		existed, _ := m.IsObjectExist(ctx, "hns", "dir/a b.txt")
+		assert.False(t, existed)
+		r, clean, err := m.GetObject(ctx, "hns", "dir/b+c.txt")
+		assert.NoError(t, err)
+		assert.Equal(t, "a", readAllForTest(t, r, clean))
+	})
+
+	t.Run("rename directory", func(t *testing.T) {
+		assert.NoError(t, m.RenameObject(ctx, "hns", "dir", "moved"))
+		_, err := m.GetObjectMeta(ctx, "hns", "dir")
+		assert.Error(t, err)
+		fi, err := m.GetObjectMeta(ctx, "hns", "moved")
+		assert.NoError(t, err)
+		assert.True(t, fi.IsDir())
+		existed, _ := m.IsObjectExist(ctx, "hns", "moved/b+c.txt")
+		assert.True(t, existed)
+	})
+
+	t.Run("rename directory into itself fails", func(t *testing.T) {
+		assert.Error(t, m.RenameObject(ctx, "hns", "moved", "moved/sub"))
+	})
+
+	t.Run("rename missing object fails", func(t *testing.T) {
+		var se *oss.ServiceError
+		assert.ErrorAs(t, m.RenameObject(ctx, "hns", "missing", "other"), &se)
+		assert.Equal(t, "NoSuchKey", se.Code)
+	})
+}
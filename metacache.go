@@ -0,0 +1,202 @@
+package ossfs
+
+import (
+	"os"
+	"slices"
+	"sync"
+	"time"
+)
+
+// metaEntry is a cached state of an object. A positive entry has exists set,
+// with the FileInfo if known, a negative entry keeps the not found error of
+// Stat if any.
+type metaEntry struct {
+	fi      os.FileInfo
+	exists  bool
+	err     error
+	expires time.Time
+}
+
+type listKey struct {
+	prefix string
+	token  string
+	count  int
+}
+
+type listEntry struct {
+	fis     []os.FileInfo
+	next    string
+	expires time.Time
+}
+
+// metaCache caches object metadata and directory listings for ttl.
+type metaCache struct {
+	ttl time.Duration
+	now func() time.Time
+
+	mu      sync.Mutex
+	entries map[string]metaEntry
+	lists   map[listKey]listEntry
+
+	// The time after which the next insert drops the expired entries, see
+	// prune.
+	pruneAt time.Time
+}
+
+func newMetaCache(ttl time.Duration) *metaCache {
+	return &metaCache{
+		ttl:     ttl,
+		now:     time.Now,
+		entries: make(map[string]metaEntry),
+		lists:   make(map[listKey]listEntry),
+	}
+}
+
+// WithMetadataCache enables caching of metadata for ttl, the results of Stat,
+// the existence checks of OpenFile and directory listings are cached,
+// including objects not found, and objects listed are cached as if they were
+// stated. Entries are dropped when the objects are changed through fs, but
+// changes made by others are not visible until the entries expire. Expired
+// entries are dropped while new ones are cached.
+//
+// A non-positive ttl disables the cache.
+func (fs *Fs) WithMetadataCache(ttl time.Duration) *Fs {
+	if ttl <= 0 {
+		fs.metaCache = nil
+		return fs
+	}
+	fs.metaCache = newMetaCache(ttl)
+	return fs
+}
+
+// FlushMetadataCache drops all cached metadata.
+func (fs *Fs) FlushMetadataCache() {
+	if c := fs.metaCache; c != nil {
+		c.mu.Lock()
+		defer c.mu.Unlock()
+		clear(c.entries)
+		clear(c.lists)
+	}
+}
+
+func (c *metaCache) get(name string) (metaEntry, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	e, found := c.entries[name]
+	if !found || !c.now().Before(e.expires) {
+		return metaEntry{}, false
+	}
+	return e, true
+}
+
+func (c *metaCache) put(name string, e metaEntry) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.prune()
+	e.expires = c.now().Add(c.ttl)
+	c.entries[name] = e
+}
+
+func (c *metaCache) getList(key listKey) ([]os.FileInfo, string, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	e, found := c.lists[key]
+	if !found || !c.now().Before(e.expires) {
+		return nil, "", false
+	}
+	return slices.Clone(e.fis), e.next, true
+}
+
+// putList caches a listed page and the FileInfo of every listed object.
+func (c *metaCache) putList(key listKey, fis []os.FileInfo, next string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.prune()
+	expires := c.now().Add(c.ttl)
+	c.lists[key] = listEntry{fis: slices.Clone(fis), next: next, expires: expires}
+	for _, fi := range fis {
+		c.entries[fi.Name()] = metaEntry{fi: fi, exists: true, expires: expires}
+	}
+}
+
+// prune drops the expired entries and listings at most once per ttl, so the
+// cache only holds what was cached in the last two ttls however many objects
+// are stated or listed over time. The caller must hold c.mu.
+func (c *metaCache) prune() {
+	now := c.now()
+	if now.Before(c.pruneAt) {
+		return
+	}
+	c.pruneAt = now.Add(c.ttl)
+	for name, e := range c.entries {
+		if !now.Before(e.expires) {
+			delete(c.entries, name)
+		}
+	}
+	for key, e := range c.lists {
+		if !now.Before(e.expires) {
+			delete(c.lists, key)
+		}
+	}
+}
+
+// invalidate drops the entry of the object, and all listings since any of
+// them may contain it.
+func (c *metaCache) invalidate(name string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	delete(c.entries, name)
+	clear(c.lists)
+}
+
+// stat returns the FileInfo of the object through the metadata cache.
+func (fs *Fs) stat(name string) (os.FileInfo, error) {
+	c := fs.metaCache
+	if c != nil {
+		if e, found := c.get(name); found && (e.fi != nil || e.err != nil) {
+			return e.fi, e.err
+		}
+	}
+	fi, err := fs.manager.GetObjectMeta(fs.ctx, fs.bucketName, name)
+	if c != nil {
+		switch {
+		case err == nil:
+			c.put(name, metaEntry{fi: fi, exists: true})
+		case isNotFound(err):
+			c.put(name, metaEntry{err: err})
+		}
+	}
+	return fi, err
+}
+
+// objectExists returns whether the object exists through the metadata cache.
+func (fs *Fs) objectExists(name string) (bool, error) {
+	c := fs.metaCache
+	if c != nil {
+		if e, found := c.get(name); found {
+			return e.exists, nil
+		}
+	}
+	existed, err := fs.manager.IsObjectExist(fs.ctx, fs.bucketName, name)
+	if c != nil && err == nil {
+		c.put(name, metaEntry{exists: existed})
+	}
+	return existed, err
+}
+
+// listObjects lists a page of the objects under prefix through the metadata
+// cache, see utils.ObjectManager.ListObjectsPage.
+func (fs *Fs) listObjects(prefix, token string, count int) ([]os.FileInfo, string, error) {
+	c := fs.metaCache
+	key := listKey{prefix: prefix, token: token, count: count}
+	if c != nil {
+		if fis, next, found := c.getList(key); found {
+			return fis, next, nil
+		}
+	}
+	fis, next, err := fs.manager.ListObjectsPage(fs.ctx, fs.bucketName, prefix, token, count)
+	if c != nil && err == nil {
+		c.putList(key, fis, next)
+	}
+	return fis, next, err
+}
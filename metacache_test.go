@@ -0,0 +1,199 @@
+package ossfs
+
+import (
+	"context"
+	"io"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/spf13/afero"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/messikiller/afero-oss/internal/utils"
+)
+
+// metaCountingManager counts the metadata requests.
+type metaCountingManager struct {
+	*utils.MemObjectManager
+	heads int
+	lists int
+}
+
+func (m *metaCountingManager) GetObjectMeta(ctx context.Context, bucket, name string) (os.FileInfo, error) {
+	m.heads++
+	return m.MemObjectManager.GetObjectMeta(ctx, bucket, name)
+}
+
+func (m *metaCountingManager) IsObjectExist(ctx context.Context, bucket, name string) (bool, error) {
+	m.heads++
+	return m.MemObjectManager.IsObjectExist(ctx, bucket, name)
+}
+
+func (m *metaCountingManager) ListObjectsPage(ctx context.Context, bucket, prefix, token string, count int) ([]os.FileInfo, string, error) {
+	m.lists++
+	return m.MemObjectManager.ListObjectsPage(ctx, bucket, prefix, token, count)
+}
+
+func TestFsWithMetadataCache(t *testing.T) {
+	fs := &Fs{}
+	got := fs.WithMetadataCache(time.Minute)
+	assert.Equal(t, fs, got)
+	assert.NotNil(t, got.metaCache)
+	assert.Equal(t, time.Minute, got.metaCache.ttl)
+
+	got = fs.WithMetadataCache(0)
+	assert.Nil(t, got.metaCache)
+	assert.NotPanics(t, got.FlushMetadataCache)
+}
+
+func TestFsMetadataCache(t *testing.T) {
+	m := &metaCountingManager{MemObjectManager: NewMemObjectManager()}
+	ctx := context.TODO()
+	_, _ = m.PutObject(ctx, "test-bucket", "dir/", strings.NewReader(""))
+	_, _ = m.PutObject(ctx, "test-bucket", "dir/a.txt", strings.NewReader("a"))
+	_, _ = m.PutObject(ctx, "test-bucket", "dir/b.txt", strings.NewReader("bb"))
+
+	now := time.Date(2025, 1, 2, 3, 4, 5, 0, time.UTC)
+	fs := NewOssFsWithManager(m, "test-bucket").WithMetadataCache(time.Minute)
+	fs.metaCache.now = func() time.Time { return now }
+
+	t.Run("stat is cached", func(t *testing.T) {
+		m.heads = 0
+		for range 3 {
+			fi, err := fs.Stat("dir/a.txt")
+			assert.NoError(t, err)
+			assert.Equal(t, int64(1), fi.Size())
+		}
+		assert.Equal(t, 1, m.heads)
+
+		f, err := fs.OpenFile("dir/a.txt", os.O_RDONLY, 0o644)
+		assert.NoError(t, err)
+		assert.Equal(t, 1, m.heads)
+		assert.NoError(t, f.Close())
+	})
+
+	t.Run("missing objects are cached", func(t *testing.T) {
+		m.heads = 0
+		for range 3 {
+			_, err := fs.Stat("dir/missing.txt")
+			assert.Error(t, err)
+			_, err = fs.OpenFile("dir/missing.txt", os.O_RDONLY, 0o644)
+			assert.Error(t, err)
+		}
+		assert.Equal(t, 2, m.heads)
+		assert.Equal(t, 1, m.lists)
+	})
+
+	t.Run("listing is cached and populates stat", func(t *testing.T) {
+		fs.FlushMetadataCache()
+		m.heads, m.lists = 0, 0
+		for range 2 {
+			f, err := fs.OpenFile("dir/", os.O_RDONLY, 0o644)
+			assert.NoError(t, err)
+			fis, err := f.Readdir(0)
+			assert.NoError(t, err)
+			assert.Len(t, fis, 2)
+			assert.NoError(t, f.Close())
+		}
+		assert.Equal(t, 1, m.lists)
+
+		m.heads = 0
+		fi, err := fs.Stat("dir/b.txt")
+		assert.NoError(t, err)
+		assert.Equal(t, int64(2), fi.Size())
+		assert.Equal(t, 0, m.heads)
+	})
+
+	t.Run("entries expire", func(t *testing.T) {
+		m.heads = 0
+		_, _ = fs.Stat("dir/a.txt")
+		now = now.Add(time.Minute)
+		_, _ = fs.Stat("dir/a.txt")
+		assert.Equal(t, 1, m.heads)
+	})
+
+	t.Run("expired entries are pruned", func(t *testing.T) {
+		fs.FlushMetadataCache()
+		d, err := fs.Open("dir/")
+		assert.NoError(t, err)
+		_, err = d.Readdir(0)
+		assert.NoError(t, err)
+		assert.NoError(t, d.Close())
+		assert.NotEmpty(t, fs.metaCache.entries)
+		assert.NotEmpty(t, fs.metaCache.lists)
+
+		now = now.Add(time.Minute)
+		_, _ = fs.Stat("dir/a.txt")
+		assert.Len(t, fs.metaCache.entries, 1)
+		assert.Empty(t, fs.metaCache.lists)
+	})
+
+	t.Run("local mutations invalidate entries", func(t *testing.T) {
+		_, err := fs.Stat("dir/c.txt")
+		assert.Error(t, err)
+		f, err := fs.Create("dir/c.txt")
+		assert.NoError(t, err)
+		_, err = f.WriteString("ccc")
+		assert.NoError(t, err)
+		assert.NoError(t, f.Close())
+
+		fi, err := fs.Stat("dir/c.txt")
+		assert.NoError(t, err)
+		assert.Equal(t, int64(3), fi.Size())
+
+		d, _ := fs.OpenFile("dir/", os.O_RDONLY, 0o644)
+		fis, err := d.Readdir(0)
+		assert.NoError(t, err)
+		assert.Len(t, fis, 3)
+		assert.NoError(t, d.Close())
+
+		assert.NoError(t, fs.Remove("dir/c.txt"))
+		_, err = fs.Stat("dir/c.txt")
+		assert.Error(t, err)
+	})
+
+	t.Run("flush drops entries", func(t *testing.T) {
+		_, _ = m.PutObject(ctx, "test-bucket", "dir/a.txt", strings.NewReader("changed"))
+		fi, _ := fs.Stat("dir/a.txt")
+		assert.Equal(t, int64(1), fi.Size())
+
+		fs.FlushMetadataCache()
+		fi, _ = fs.Stat("dir/a.txt")
+		assert.Equal(t, int64(7), fi.Size())
+	})
+}
+
+// statDuringWriteManager stats the object through fs before every write, as a
+// concurrent Stat running during the mutation would.
+type statDuringWriteManager struct {
+	*utils.MemObjectManager
+	fs *Fs
+}
+
+func (m *statDuringWriteManager) PutObject(ctx context.Context, bucket, name string, reader io.Reader) (bool, error) {
+	_, _ = m.fs.Stat(name)
+	return m.MemObjectManager.PutObject(ctx, bucket, name, reader)
+}
+
+func (m *statDuringWriteManager) DeleteObject(ctx context.Context, bucket, name string) error {
+	_, _ = m.fs.Stat(name)
+	return m.MemObjectManager.DeleteObject(ctx, bucket, name)
+}
+
+func TestFsMetadataCacheInvalidatedAfterMutation(t *testing.T) {
+	m := &statDuringWriteManager{MemObjectManager: NewMemObjectManager()}
+	fs := NewOssFsWithManager(m, "test-bucket").WithMetadataCache(time.Minute)
+	m.fs = fs
+	_, _ = m.MemObjectManager.PutObject(context.TODO(), "test-bucket", "a.txt", strings.NewReader("a"))
+
+	assert.NoError(t, afero.WriteFile(fs, "a.txt", []byte("hello"), 0o644))
+	fi, err := fs.Stat("a.txt")
+	assert.NoError(t, err)
+	assert.Equal(t, int64(5), fi.Size())
+
+	assert.NoError(t, fs.Remove("a.txt"))
+	_, err = fs.Stat("a.txt")
+	assert.ErrorIs(t, err, os.ErrNotExist)
+}
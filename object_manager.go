@@ -0,0 +1,30 @@
+package ossfs
+
+import "github.com/messikiller/afero-oss/internal/utils"
+
+// ObjectManager is the interface used by Fs to access objects of a bucket.
+type ObjectManager = utils.ObjectManager
+
+// CleanUp releases the resources held by a reader returned from ObjectManager.
+type CleanUp = utils.CleanUp
+
+// UploadedPart identifies a part uploaded by ObjectManager.UploadPart, it's
+// needed to complete the multipart upload.
+type UploadedPart = utils.UploadedPart
+
+// DeleteError reports an object which ObjectManager.DeleteObjects, and so
+// Fs.RemoveAll, failed to delete.
+type DeleteError = utils.DeleteError
+
+// ErrNotDeleted is the error of an object which a bulk deletion succeeded
+// without reporting it as deleted.
+var ErrNotDeleted = utils.ErrNotDeleted
+
+// MemObjectManager is an in-memory ObjectManager, see NewMemObjectManager.
+type MemObjectManager = utils.MemObjectManager
+
+// NewMemObjectManager creates an empty in-memory ObjectManager, it can be used
+// with NewOssFsWithManager to run Fs without a real OSS bucket.
+func NewMemObjectManager() *MemObjectManager {
+	return utils.NewMemObjectManager()
+}
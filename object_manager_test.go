@@ -0,0 +1,88 @@
+package ossfs_test
+
+import (
+	"context"
+	"io"
+	"iter"
+	"os"
+
+	ossfs "github.com/messikiller/afero-oss"
+)
+
+// externalManager checks that ObjectManager can be implemented outside of
+// the module.
+type externalManager struct{}
+
+var _ ossfs.ObjectManager = externalManager{}
+
+func (externalManager) GetObject(ctx context.Context, bucket, name string) (io.Reader, ossfs.CleanUp, error) {
+	return nil, nil, nil
+}
+
+func (externalManager) GetObjectPart(ctx context.Context, bucket, name string, start, end int64) (io.Reader, ossfs.CleanUp, error) {
+	return nil, nil, nil
+}
+
+func (externalManager) DeleteObject(ctx context.Context, bucket, name string) error {
+	return nil
+}
+
+func (externalManager) DeleteObjects(ctx context.Context, bucket string, names []string) error {
+	return nil
+}
+
+func (externalManager) IsObjectExist(ctx context.Context, bucket, name string) (bool, error) {
+	return false, nil
+}
+
+func (externalManager) PutObject(ctx context.Context, bucket, name string, reader io.Reader) (bool, error) {
+	return false, nil
+}
+
+func (externalManager) CopyObject(ctx context.Context, bucket, srcName, targetName string) error {
+	return nil
+}
+
+func (externalManager) RenameObject(ctx context.Context, bucket, srcName, targetName string) error {
+	return nil
+}
+
+func (externalManager) CreateDirectory(ctx context.Context, bucket, name string) error {
+	return nil
+}
+
+func (externalManager) GetObjectMeta(ctx context.Context, bucket, name string) (os.FileInfo, error) {
+	return nil, nil
+}
+
+func (externalManager) ListObjects(ctx context.Context, bucket, prefix string, count int) ([]os.FileInfo, error) {
+	return nil, nil
+}
+
+func (externalManager) ListObjectsPage(ctx context.Context, bucket, prefix, token string, count int) ([]os.FileInfo, string, error) {
+	return nil, "", nil
+}
+
+func (externalManager) ListAllObjects(ctx context.Context, bucket, prefix string) ([]os.FileInfo, error) {
+	return nil, nil
+}
+
+func (externalManager) IterObjects(ctx context.Context, bucket, prefix string, recursive bool) iter.Seq2[os.FileInfo, error] {
+	return func(yield func(os.FileInfo, error) bool) {}
+}
+
+func (externalManager) InitiateMultipartUpload(ctx context.Context, bucket, name string) (string, error) {
+	return "", nil
+}
+
+func (externalManager) UploadPart(ctx context.Context, bucket, name, uploadId string, partNumber int32, reader io.Reader) (ossfs.UploadedPart, error) {
+	return ossfs.UploadedPart{}, nil
+}
+
+func (externalManager) CompleteMultipartUpload(ctx context.Context, bucket, name, uploadId string, parts []ossfs.UploadedPart) error {
+	return nil
+}
+
+func (externalManager) AbortMultipartUpload(ctx context.Context, bucket, name, uploadId string) error {
+	return nil
+}
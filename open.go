@@ -0,0 +1,170 @@
+package ossfs
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/aliyun/alibabacloud-oss-go-sdk-v2/oss/credentials"
+	"github.com/spf13/afero"
+)
+
+// Opener creates a file system from a URL of its scheme, see RegisterScheme.
+type Opener func(u *url.URL) (afero.Fs, error)
+
+var (
+	schemesMu sync.RWMutex
+	schemes   = map[string]Opener{
+		"oss":  openOSS,
+		"file": openFile,
+		"mem":  openMem,
+	}
+)
+
+// RegisterScheme registers the opener of URLs of scheme for Open, replacing
+// the opener registered before if any.
+func RegisterScheme(scheme string, opener Opener) {
+	schemesMu.Lock()
+	defer schemesMu.Unlock()
+	schemes[strings.ToLower(scheme)] = opener
+}
+
+// Open creates a file system from a URL, so the backend can be switched by
+// configuration. The schemes below are registered by default, and others can
+// be registered by RegisterScheme.
+//
+//	oss://[accessKeyId:accessKeySecret@]bucket[/prefix]?region=cn-hangzhou[&...]
+//	file:///path/to/dir
+//	mem://
+//
+// An oss URL creates an Fs of the bucket confined to the prefix if any, see
+// Fs.WithKeyPrefix. The requests are signed by the access key pair in the
+// URL, or else by the credentials of the profile parameter in the credentials
+// file, see FileCredentials, or else by the credentials in environment
+// variables, see EnvCredentials. The parameters are:
+//
+//   - region: the region of the bucket, required
+//   - endpoint: the endpoint, see OSSWithEndpoint
+//   - internal, accelerate, dualstack: use the internal, accelerated or
+//     dual-stack endpoint
+//   - cname: the endpoint is a custom domain name, see OSSWithUseCName
+//   - path_style: use path request style
+//   - hns: the bucket has hierarchical namespace, see
+//     Fs.WithHierarchicalNamespace
+//   - profile: the profile in the credentials file
+//
+// A file URL creates an afero.OsFs confined to the path if any, and a mem URL
+// creates an afero.MemMapFs.
+func Open(rawURL string) (afero.Fs, error) {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return nil, err
+	}
+	schemesMu.RLock()
+	opener, found := schemes[strings.ToLower(u.Scheme)]
+	schemesMu.RUnlock()
+	if !found {
+		return nil, fmt.Errorf("ossfs: unsupported scheme %q", u.Scheme)
+	}
+	return opener(u)
+}
+
+func openOSS(u *url.URL) (afero.Fs, error) {
+	bucket := u.Host
+	if bucket == "" {
+		return nil, errors.New("ossfs: no bucket in oss URL")
+	}
+	q := u.Query()
+	region := q.Get("region")
+	if region == "" {
+		return nil, errors.New("ossfs: no region in oss URL")
+	}
+
+	var (
+		opts     []Option
+		hns      bool
+		cname    bool
+		endpoint string
+		profile  string
+	)
+	for name := range q {
+		value := q.Get(name)
+		switch name {
+		case "region":
+		case "endpoint":
+			endpoint = value
+		case "profile":
+			profile = value
+		case "internal", "accelerate", "dualstack", "cname", "path_style", "hns":
+			enabled, err := strconv.ParseBool(value)
+			if err != nil {
+				return nil, fmt.Errorf("ossfs: invalid parameter %s of oss URL: %w", name, err)
+			}
+			if !enabled {
+				continue
+			}
+			switch name {
+			case "internal":
+				opts = append(opts, OSSWithUseInternalEndpoint())
+			case "accelerate":
+				opts = append(opts, OSSWithUseAccelerateEndpoint())
+			case "dualstack":
+				opts = append(opts, OSSWithUseDualStackEndpoint())
+			case "cname":
+				cname = true
+			case "path_style":
+				opts = append(opts, OSSWithUsePathStyle())
+			case "hns":
+				hns = true
+			}
+		default:
+			return nil, fmt.Errorf("ossfs: unknown parameter %s of oss URL", name)
+		}
+	}
+	switch {
+	case cname:
+		opts = append(opts, OSSWithUseCName(endpoint))
+	case endpoint != "":
+		opts = append(opts, OSSWithEndpoint(endpoint))
+	}
+	if hns {
+		opts = append(opts, WithHierarchicalNamespace())
+	}
+
+	var provider credentials.CredentialsProvider
+	switch {
+	case u.User != nil:
+		secret, _ := u.User.Password()
+		provider = credentials.NewStaticCredentialsProvider(u.User.Username(), secret)
+	case profile != "":
+		p, err := FileCredentials("", profile)
+		if err != nil {
+			return nil, err
+		}
+		provider = p
+	default:
+		provider = EnvCredentials()
+	}
+
+	if prefix := strings.Trim(u.Path, "/"); prefix != "" {
+		opts = append(opts, WithKeyPrefix(prefix))
+	}
+	return NewOssFsWithCredentials(provider, region, bucket, opts...), nil
+}
+
+func openFile(u *url.URL) (afero.Fs, error) {
+	if u.Host != "" && u.Host != "localhost" {
+		return nil, fmt.Errorf("ossfs: unsupported host %q of file URL", u.Host)
+	}
+	if u.Path == "" || u.Path == "/" {
+		return afero.NewOsFs(), nil
+	}
+	return afero.NewBasePathFs(afero.NewOsFs(), u.Path), nil
+}
+
+func openMem(*url.URL) (afero.Fs, error) {
+	return afero.NewMemMapFs(), nil
+}
@@ -0,0 +1,109 @@
+package ossfs
+
+import (
+	"net/url"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/spf13/afero"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/messikiller/afero-oss/osstest"
+)
+
+func TestOpen(t *testing.T) {
+	srv := osstest.NewServer("test-bucket")
+	defer srv.Close()
+	query := "?region=cn-hangzhou&path_style=true&endpoint=" + url.QueryEscape(srv.URL)
+
+	t.Run("open oss url with prefix", func(t *testing.T) {
+		fs, err := Open("oss://ak:sk@test-bucket/app/data/" + query)
+		assert.NoError(t, err)
+		assert.NoError(t, afero.WriteFile(fs, "a.txt", []byte("hello"), 0o644))
+		b, err := afero.ReadFile(fs, "/a.txt")
+		assert.NoError(t, err)
+		assert.Equal(t, "hello", string(b))
+
+		root := NewOssFs("ak", "sk", "cn-hangzhou", "test-bucket", OSSWithEndpoint(srv.URL), OSSWithUsePathStyle())
+		existed, err := afero.Exists(root, "app/data/a.txt")
+		assert.NoError(t, err)
+		assert.True(t, existed)
+	})
+
+	t.Run("open oss url with options", func(t *testing.T) {
+		t.Setenv("OSS_ACCESS_KEY_ID", "ak")
+		t.Setenv("OSS_ACCESS_KEY_SECRET", "sk")
+		fs, err := Open("oss://test-bucket" + query + "&hns=true&internal=false")
+		assert.NoError(t, err)
+		assert.IsType(t, &Fs{}, fs)
+		ossFs := fs.(*Fs)
+		assert.True(t, ossFs.hns)
+		assert.Equal(t, srv.URL, *ossFs.ossCfg.Endpoint)
+		assert.True(t, *ossFs.ossCfg.UsePathStyle)
+		assert.Nil(t, ossFs.ossCfg.UseInternalEndpoint)
+		assert.NoError(t, afero.WriteFile(fs, "b.txt", []byte("hello"), 0o644))
+	})
+
+	t.Run("open oss url with cname", func(t *testing.T) {
+		for cname, expected := range map[string]bool{"true": true, "false": false, "0": false} {
+			fs, err := Open("oss://ak:sk@test-bucket?region=r&endpoint=http://x&cname=" + cname)
+			assert.NoError(t, err)
+			cfg := fs.(*Fs).ossCfg
+			if assert.NotNil(t, cfg.Endpoint, cname) {
+				assert.Equal(t, "http://x", *cfg.Endpoint)
+			}
+			if expected {
+				assert.True(t, *cfg.UseCName)
+			} else {
+				assert.Nil(t, cfg.UseCName)
+			}
+		}
+	})
+
+	t.Run("invalid oss urls", func(t *testing.T) {
+		for rawURL, msg := range map[string]string{
+			"oss:///prefix?region=cn-hangzhou":        "no bucket",
+			"oss://bucket":                            "no region",
+			"oss://bucket?region=r&unknown=1":         "unknown parameter unknown",
+			"oss://bucket?region=r&internal=sometime": "invalid parameter internal",
+			"s3://bucket":                             `unsupported scheme "s3"`,
+		} {
+			_, err := Open(rawURL)
+			assert.ErrorContains(t, err, msg, rawURL)
+		}
+	})
+
+	t.Run("open file url", func(t *testing.T) {
+		dir := t.TempDir()
+		fs, err := Open("file://" + filepath.ToSlash(dir))
+		assert.NoError(t, err)
+		assert.NoError(t, afero.WriteFile(fs, "a.txt", []byte("hello"), 0o644))
+		b, err := os.ReadFile(filepath.Join(dir, "a.txt"))
+		assert.NoError(t, err)
+		assert.Equal(t, "hello", string(b))
+
+		fs, err = Open("file:///")
+		assert.NoError(t, err)
+		assert.IsType(t, &afero.OsFs{}, fs)
+	})
+
+	t.Run("open mem url", func(t *testing.T) {
+		fs, err := Open("mem://")
+		assert.NoError(t, err)
+		assert.IsType(t, &afero.MemMapFs{}, fs)
+	})
+
+	t.Run("register scheme", func(t *testing.T) {
+		mem := afero.NewMemMapFs()
+		RegisterScheme("Test", func(u *url.URL) (afero.Fs, error) {
+			return afero.NewBasePathFs(mem, u.Path), nil
+		})
+		fs, err := Open("test:///root")
+		assert.NoError(t, err)
+		assert.NoError(t, afero.WriteFile(fs, "a.txt", []byte("hello"), 0o644))
+		existed, err := afero.Exists(mem, "/root/a.txt")
+		assert.NoError(t, err)
+		assert.True(t, existed)
+	})
+}
@@ -1,9 +1,19 @@
 package ossfs
 
-import "github.com/aliyun/alibabacloud-oss-go-sdk-v2/oss"
+import (
+	"github.com/aliyun/alibabacloud-oss-go-sdk-v2/oss"
+	"github.com/aliyun/alibabacloud-oss-go-sdk-v2/oss/credentials"
+)
 
 type OSSOptionFunc func(c *oss.Config)
 
+// Specify the provider of credentials, e.g. EnvCredentials or STSCredentials.
+func OSSWithCredentialsProvider(provider credentials.CredentialsProvider) OSSOptionFunc {
+	return func(c *oss.Config) {
+		c.WithCredentialsProvider(provider)
+	}
+}
+
 // Specify the endpoint.
 func OSSWithEndpoint(endpoint string) OSSOptionFunc {
 	return func(c *oss.Config) {
@@ -4,6 +4,7 @@ import (
 	"testing"
 
 	"github.com/aliyun/alibabacloud-oss-go-sdk-v2/oss"
+	"github.com/aliyun/alibabacloud-oss-go-sdk-v2/oss/credentials"
 	"github.com/stretchr/testify/assert"
 )
 
@@ -106,3 +107,12 @@ func TestOSSWithUserAgent(t *testing.T) {
 		assert.Equal(t, ua, *ossCfg.UserAgent)
 	})
 }
+
+func TestOSSWithCredentialsProvider(t *testing.T) {
+	ossCfg := getNewOssCfgForTest()
+	provider := credentials.NewStaticCredentialsProvider("ak", "sk")
+	t.Run("test OSSWithCredentialsProvider", func(t *testing.T) {
+		OSSWithCredentialsProvider(provider)(ossCfg)
+		assert.Equal(t, provider, ossCfg.CredentialsProvider)
+	})
+}
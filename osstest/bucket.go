@@ -0,0 +1,215 @@
+package osstest
+
+import (
+	"crypto/md5"
+	"encoding/base64"
+	"encoding/xml"
+	"io"
+	"net/http"
+	"net/url"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+const (
+	defaultMaxKeys = 100
+	maxMaxKeys     = 1000
+	maxDeleteKeys  = 1000
+)
+
+func (s *Server) serveBucket(w http.ResponseWriter, r *http.Request, bucketName string) {
+	q := r.URL.Query()
+	switch {
+	case r.Method == http.MethodGet && q.Get("list-type") == "2":
+		s.listObjectsV2(w, r, bucketName)
+	case r.Method == http.MethodPost && q.Has("delete"):
+		s.deleteMultipleObjects(w, r, bucketName)
+	default:
+		writeError(w, r, http.StatusNotImplemented, "NotImplemented", "The operation is not supported.")
+	}
+}
+
+type listContents struct {
+	Key          string `xml:"Key"`
+	LastModified string `xml:"LastModified"`
+	ETag         string `xml:"ETag"`
+	Type         string `xml:"Type"`
+	Size         int64  `xml:"Size"`
+	StorageClass string `xml:"StorageClass"`
+}
+
+type listCommonPrefix struct {
+	Prefix string `xml:"Prefix"`
+}
+
+type listBucketResult struct {
+	XMLName               xml.Name           `xml:"ListBucketResult"`
+	Name                  string             `xml:"Name"`
+	Prefix                string             `xml:"Prefix"`
+	StartAfter            string             `xml:"StartAfter,omitempty"`
+	MaxKeys               int                `xml:"MaxKeys"`
+	Delimiter             string             `xml:"Delimiter,omitempty"`
+	EncodingType          string             `xml:"EncodingType,omitempty"`
+	IsTruncated           bool               `xml:"IsTruncated"`
+	ContinuationToken     string             `xml:"ContinuationToken,omitempty"`
+	NextContinuationToken string             `xml:"NextContinuationToken,omitempty"`
+	KeyCount              int                `xml:"KeyCount"`
+	Contents              []listContents     `xml:"Contents"`
+	CommonPrefixes        []listCommonPrefix `xml:"CommonPrefixes"`
+}
+
+// listObjectsV2 lists objects in lexicographical order, keys sharing the same
+// part up to the delimiter after the prefix are rolled up as a common prefix
+// and each common prefix counts as a single entry against max-keys. The
+// continuation token is the last returned entry, encoded to be opaque.
+func (s *Server) listObjectsV2(w http.ResponseWriter, r *http.Request, bucketName string) {
+	q := r.URL.Query()
+	prefix := q.Get("prefix")
+	delimiter := q.Get("delimiter")
+	encode := strings.EqualFold(q.Get("encoding-type"), "url")
+
+	maxKeys := defaultMaxKeys
+	if v := q.Get("max-keys"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 1 || n > maxMaxKeys {
+			writeError(w, r, http.StatusBadRequest, "InvalidArgument", "The max-keys is invalid.")
+			return
+		}
+		maxKeys = n
+	}
+
+	marker := q.Get("start-after")
+	if token := q.Get("continuation-token"); token != "" {
+		b, err := base64.RawURLEncoding.DecodeString(token)
+		if err != nil {
+			writeError(w, r, http.StatusBadRequest, "InvalidArgument", "The continuation-token is invalid.")
+			return
+		}
+		marker = string(b)
+	}
+
+	s.mu.Lock()
+	objects := s.buckets[bucketName].objects
+	keys := make([]string, 0, len(objects))
+	for k := range objects {
+		if strings.HasPrefix(k, prefix) {
+			keys = append(keys, k)
+		}
+	}
+	slices.Sort(keys)
+
+	escape := func(v string) string {
+		if encode {
+			return url.QueryEscape(v)
+		}
+		return v
+	}
+
+	res := &listBucketResult{
+		Name:              bucketName,
+		Prefix:            escape(prefix),
+		StartAfter:        escape(q.Get("start-after")),
+		MaxKeys:           maxKeys,
+		Delimiter:         escape(delimiter),
+		ContinuationToken: q.Get("continuation-token"),
+	}
+	if encode {
+		res.EncodingType = "url"
+	}
+
+	last := ""
+	for _, k := range keys {
+		entry := k
+		if delimiter != "" {
+			if i := strings.Index(k[len(prefix):], delimiter); i >= 0 {
+				entry = k[:len(prefix)+i+len(delimiter)]
+			}
+		}
+		if entry <= marker || entry == last {
+			continue
+		}
+		if res.KeyCount == maxKeys {
+			res.IsTruncated = true
+			res.NextContinuationToken = base64.RawURLEncoding.EncodeToString([]byte(last))
+			break
+		}
+		if entry != k {
+			res.CommonPrefixes = append(res.CommonPrefixes, listCommonPrefix{Prefix: escape(entry)})
+		} else {
+			obj := objects[k]
+			res.Contents = append(res.Contents, listContents{
+				Key:          escape(k),
+				LastModified: obj.lastModified.Format(ossTimeFormat),
+				ETag:         obj.etag,
+				Type:         "Normal",
+				Size:         int64(len(obj.data)),
+				StorageClass: obj.storageClass,
+			})
+		}
+		res.KeyCount++
+		last = entry
+	}
+	s.mu.Unlock()
+
+	writeXML(w, http.StatusOK, res)
+}
+
+type deleteRequest struct {
+	XMLName xml.Name `xml:"Delete"`
+	Quiet   bool     `xml:"Quiet"`
+	Objects []struct {
+		Key string `xml:"Key"`
+	} `xml:"Object"`
+}
+
+type deletedObject struct {
+	Key string `xml:"Key"`
+}
+
+type deleteResult struct {
+	XMLName      xml.Name        `xml:"DeleteResult"`
+	EncodingType string          `xml:"EncodingType,omitempty"`
+	Deleted      []deletedObject `xml:"Deleted"`
+}
+
+// deleteMultipleObjects deletes at most 1000 objects, keys of missing objects
+// are reported as deleted too, and none of them are reported in quiet mode.
+func (s *Server) deleteMultipleObjects(w http.ResponseWriter, r *http.Request, bucketName string) {
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		writeError(w, r, http.StatusBadRequest, "MalformedXML", "The XML you provided was not well-formed.")
+		return
+	}
+	sum := md5.Sum(body)
+	if r.Header.Get("Content-MD5") != base64.StdEncoding.EncodeToString(sum[:]) {
+		writeError(w, r, http.StatusBadRequest, "InvalidDigest", "The Content-MD5 you specified was invalid.")
+		return
+	}
+	var req deleteRequest
+	if err := xml.Unmarshal(body, &req); err != nil || len(req.Objects) == 0 || len(req.Objects) > maxDeleteKeys {
+		writeError(w, r, http.StatusBadRequest, "MalformedXML", "The XML you provided was not well-formed.")
+		return
+	}
+
+	encode := strings.EqualFold(r.URL.Query().Get("encoding-type"), "url")
+	res := &deleteResult{}
+	if encode {
+		res.EncodingType = "url"
+	}
+	s.mu.Lock()
+	for _, o := range req.Objects {
+		delete(s.buckets[bucketName].objects, o.Key)
+		if req.Quiet {
+			continue
+		}
+		key := o.Key
+		if encode {
+			key = url.QueryEscape(key)
+		}
+		res.Deleted = append(res.Deleted, deletedObject{Key: key})
+	}
+	s.mu.Unlock()
+
+	writeXML(w, http.StatusOK, res)
+}
@@ -0,0 +1,114 @@
+package osstest
+
+import (
+	"net/http"
+	"strings"
+)
+
+// EnableHierarchicalNamespace enables hierarchical namespace of the bucket,
+// which supports the Rename and CreateDirectory operations. A directory is
+// kept as a directory marker, i.e. an empty object named with a trailing "/",
+// and it's stated as an object of Directory type by its name without the
+// trailing "/".
+func (s *Server) EnableHierarchicalNamespace(bucketName string) {
+	s.CreateBucket(bucketName)
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.buckets[bucketName].hns = true
+}
+
+// serveNamespace serves the operations of hierarchical namespace, it returns
+// false if the request is not one of them.
+func (s *Server) serveNamespace(w http.ResponseWriter, r *http.Request, bucketName, key string) bool {
+	q := r.URL.Query()
+	if r.Method != http.MethodPost || (!q.Has("x-oss-rename") && !q.Has("x-oss-dir")) {
+		return false
+	}
+
+	s.mu.Lock()
+	hns := s.buckets[bucketName].hns
+	s.mu.Unlock()
+	if !hns {
+		writeError(w, r, http.StatusBadRequest, "OperationNotSupported", "The operation is not supported for this bucket.")
+		return true
+	}
+
+	if q.Has("x-oss-dir") {
+		s.createDirectory(w, r, bucketName, key)
+	} else {
+		s.rename(w, r, bucketName, key)
+	}
+	return true
+}
+
+func (s *Server) createDirectory(w http.ResponseWriter, r *http.Request, bucketName, key string) {
+	dir := strings.TrimSuffix(key, "/") + "/"
+	obj := newObject(nil, r.Header)
+	s.mu.Lock()
+	s.buckets[bucketName].objects[dir] = obj
+	s.mu.Unlock()
+	w.WriteHeader(http.StatusOK)
+}
+
+// rename moves the object named by the x-oss-rename-source header to key, or
+// all objects under it if it's a directory.
+func (s *Server) rename(w http.ResponseWriter, r *http.Request, bucketName, key string) {
+	srcBucket, srcKey, err := parseCopySource(r.Header.Get("x-oss-rename-source"))
+	if err != nil || srcBucket != bucketName {
+		writeError(w, r, http.StatusBadRequest, "InvalidArgument", "The rename source is invalid.")
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	objects := s.buckets[bucketName].objects
+	if obj, found := objects[srcKey]; found {
+		delete(objects, srcKey)
+		objects[key] = obj
+		w.WriteHeader(http.StatusOK)
+		return
+	}
+
+	srcDir, dstDir := strings.TrimSuffix(srcKey, "/")+"/", strings.TrimSuffix(key, "/")+"/"
+	if strings.HasPrefix(dstDir, srcDir) {
+		writeError(w, r, http.StatusBadRequest, "InvalidArgument", "The rename target is under the source directory.")
+		return
+	}
+	var keys []string
+	for k := range objects {
+		if strings.HasPrefix(k, srcDir) {
+			keys = append(keys, k)
+		}
+	}
+	if len(keys) == 0 {
+		writeError(w, r, http.StatusNotFound, "NoSuchKey", "The specified key does not exist.")
+		return
+	}
+	for _, k := range keys {
+		objects[dstDir+strings.TrimPrefix(k, srcDir)] = objects[k]
+		delete(objects, k)
+	}
+	w.WriteHeader(http.StatusOK)
+}
+
+// headDirectory responds to a HEAD request of a directory of hierarchical
+// namespace, it returns false if key is not a directory.
+func (s *Server) headDirectory(w http.ResponseWriter, bucketName, key string) bool {
+	s.mu.Lock()
+	b := s.buckets[bucketName]
+	hns := b.hns
+	_, isObject := b.objects[key]
+	dir, isDir := b.objects[key+"/"]
+	s.mu.Unlock()
+	if !hns || isObject || !isDir || strings.HasSuffix(key, "/") {
+		return false
+	}
+
+	h := w.Header()
+	h.Set("ETag", dir.etag)
+	h.Set("Last-Modified", dir.lastModified.Format(http.TimeFormat))
+	h.Set("x-oss-object-type", "Directory")
+	h.Set("Content-Length", "0")
+	w.WriteHeader(http.StatusOK)
+	return true
+}
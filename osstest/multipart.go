@@ -0,0 +1,264 @@
+package osstest
+
+import (
+	"bytes"
+	"crypto/md5"
+	"encoding/hex"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"maps"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+)
+
+type upload struct {
+	bucket string
+	key    string
+	header http.Header
+	parts  map[int32][]byte
+}
+
+// serveMultipart serves the multipart upload operations of an object, it
+// returns false if the request is not one of them.
+func (s *Server) serveMultipart(w http.ResponseWriter, r *http.Request, bucketName, key string) bool {
+	q := r.URL.Query()
+	_, initiate := q["uploads"]
+	uploadId := q.Get("uploadId")
+	switch {
+	case r.Method == http.MethodPost && initiate:
+		s.initiateMultipartUpload(w, r, bucketName, key)
+	case r.Method == http.MethodPut && uploadId != "" && r.Header.Get("x-oss-copy-source") != "":
+		s.uploadPartCopy(w, r, bucketName, key, uploadId)
+	case r.Method == http.MethodPut && uploadId != "":
+		s.uploadPart(w, r, bucketName, key, uploadId)
+	case r.Method == http.MethodPost && uploadId != "":
+		s.completeMultipartUpload(w, r, bucketName, key, uploadId)
+	case r.Method == http.MethodDelete && uploadId != "":
+		s.abortMultipartUpload(w, r, bucketName, key, uploadId)
+	default:
+		return false
+	}
+	return true
+}
+
+// Uploads returns the number of multipart uploads neither completed nor
+// aborted yet.
+func (s *Server) Uploads() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return len(s.uploads)
+}
+
+// lookupUpload returns the upload or writes a NoSuchUpload error if it
+// doesn't exist.
+func (s *Server) lookupUpload(w http.ResponseWriter, r *http.Request, bucketName, key, uploadId string) (*upload, bool) {
+	s.mu.Lock()
+	u, found := s.uploads[uploadId]
+	s.mu.Unlock()
+	if !found || u.bucket != bucketName || u.key != key {
+		writeError(w, r, http.StatusNotFound, "NoSuchUpload", "The specified upload does not exist.")
+		return nil, false
+	}
+	return u, true
+}
+
+type initiateMultipartUploadResult struct {
+	XMLName      xml.Name `xml:"InitiateMultipartUploadResult"`
+	Bucket       string   `xml:"Bucket"`
+	Key          string   `xml:"Key"`
+	UploadId     string   `xml:"UploadId"`
+	EncodingType string   `xml:"EncodingType,omitempty"`
+}
+
+func (s *Server) initiateMultipartUpload(w http.ResponseWriter, r *http.Request, bucketName, key string) {
+	uploadId := fmt.Sprintf("%032X", s.seq.Add(1))
+	s.mu.Lock()
+	s.uploads[uploadId] = &upload{
+		bucket: bucketName,
+		key:    key,
+		header: r.Header.Clone(),
+		parts:  make(map[int32][]byte),
+	}
+	s.mu.Unlock()
+
+	res := &initiateMultipartUploadResult{
+		Bucket:   bucketName,
+		Key:      key,
+		UploadId: uploadId,
+	}
+	if strings.EqualFold(r.URL.Query().Get("encoding-type"), "url") {
+		res.Key = url.QueryEscape(key)
+		res.EncodingType = "url"
+	}
+	writeXML(w, http.StatusOK, res)
+}
+
+func (s *Server) uploadPart(w http.ResponseWriter, r *http.Request, bucketName, key, uploadId string) {
+	partNumber, err := strconv.ParseInt(r.URL.Query().Get("partNumber"), 10, 32)
+	if err != nil || partNumber < 1 || partNumber > 10000 {
+		writeError(w, r, http.StatusBadRequest, "InvalidArgument", "The partNumber is invalid.")
+		return
+	}
+	u, ok := s.lookupUpload(w, r, bucketName, key, uploadId)
+	if !ok {
+		return
+	}
+	data, err := io.ReadAll(r.Body)
+	if err != nil {
+		writeError(w, r, http.StatusBadRequest, "IncompleteBody", err.Error())
+		return
+	}
+	s.mu.Lock()
+	u.parts[int32(partNumber)] = data
+	s.mu.Unlock()
+
+	part := newObject(data, r.Header)
+	w.Header().Set("ETag", part.etag)
+	w.Header().Set("x-oss-hash-crc64ecma", strconv.FormatUint(part.crc64, 10))
+	w.WriteHeader(http.StatusOK)
+}
+
+type copyPartResult struct {
+	XMLName      xml.Name `xml:"CopyPartResult"`
+	LastModified string   `xml:"LastModified"`
+	ETag         string   `xml:"ETag"`
+}
+
+// uploadPartCopy uploads a part copied from the x-oss-copy-source-range of
+// the source object, or the whole object if the range is absent.
+func (s *Server) uploadPartCopy(w http.ResponseWriter, r *http.Request, bucketName, key, uploadId string) {
+	partNumber, err := strconv.ParseInt(r.URL.Query().Get("partNumber"), 10, 32)
+	if err != nil || partNumber < 1 || partNumber > 10000 {
+		writeError(w, r, http.StatusBadRequest, "InvalidArgument", "The partNumber is invalid.")
+		return
+	}
+	srcBucket, srcKey, err := parseCopySource(r.Header.Get("x-oss-copy-source"))
+	if err != nil {
+		writeError(w, r, http.StatusBadRequest, "InvalidArgument", err.Error())
+		return
+	}
+	u, ok := s.lookupUpload(w, r, bucketName, key, uploadId)
+	if !ok {
+		return
+	}
+
+	s.mu.Lock()
+	b, found := s.buckets[srcBucket]
+	var src *object
+	if found {
+		src, found = b.objects[srcKey]
+	}
+	s.mu.Unlock()
+	if !found {
+		writeError(w, r, http.StatusNotFound, "NoSuchKey", "The specified key does not exist.")
+		return
+	}
+
+	data := src.data
+	if rng := r.Header.Get("x-oss-copy-source-range"); rng != "" {
+		var start, end int
+		if _, err := fmt.Sscanf(rng, "bytes=%d-%d", &start, &end); err != nil ||
+			start < 0 || start > end || end >= len(src.data) {
+			writeError(w, r, http.StatusRequestedRangeNotSatisfiable, "InvalidRange", "The requested range cannot be satisfied.")
+			return
+		}
+		data = src.data[start : end+1]
+	}
+	data = bytes.Clone(data)
+
+	s.mu.Lock()
+	u.parts[int32(partNumber)] = data
+	s.mu.Unlock()
+
+	writeXML(w, http.StatusOK, &copyPartResult{
+		LastModified: src.lastModified.Format(ossTimeFormat),
+		ETag:         etagOf(data),
+	})
+}
+
+type completeMultipartUpload struct {
+	Parts []struct {
+		PartNumber int32  `xml:"PartNumber"`
+		ETag       string `xml:"ETag"`
+	} `xml:"Part"`
+}
+
+type completeMultipartUploadResult struct {
+	XMLName      xml.Name `xml:"CompleteMultipartUploadResult"`
+	EncodingType string   `xml:"EncodingType,omitempty"`
+	Location     string   `xml:"Location"`
+	Bucket       string   `xml:"Bucket"`
+	Key          string   `xml:"Key"`
+	ETag         string   `xml:"ETag"`
+}
+
+// completeMultipartUpload concatenates the listed parts, which must be in
+// ascending order and match the ETags returned by uploadPart. The ETag of the
+// object is the MD5 of the part MD5s followed by the number of parts.
+func (s *Server) completeMultipartUpload(w http.ResponseWriter, r *http.Request, bucketName, key, uploadId string) {
+	var req completeMultipartUpload
+	if err := xml.NewDecoder(r.Body).Decode(&req); err != nil || len(req.Parts) == 0 {
+		writeError(w, r, http.StatusBadRequest, "MalformedXML", "The XML you provided was not well-formed.")
+		return
+	}
+	u, ok := s.lookupUpload(w, r, bucketName, key, uploadId)
+	if !ok {
+		return
+	}
+
+	s.mu.Lock()
+	parts := maps.Clone(u.parts)
+	s.mu.Unlock()
+
+	var data bytes.Buffer
+	sums := md5.New()
+	for i, p := range req.Parts {
+		if i > 0 && p.PartNumber <= req.Parts[i-1].PartNumber {
+			writeError(w, r, http.StatusBadRequest, "InvalidPartOrder", "The list of parts was not in ascending order.")
+			return
+		}
+		part, found := parts[p.PartNumber]
+		if !found || !strings.EqualFold(strings.Trim(p.ETag, `"`), strings.Trim(etagOf(part), `"`)) {
+			writeError(w, r, http.StatusBadRequest, "InvalidPart", "One or more of the specified parts could not be found or the specified entity tag might not have matched the part's entity tag.")
+			return
+		}
+		sum := md5.Sum(part)
+		sums.Write(sum[:])
+		data.Write(part)
+	}
+
+	obj := newObject(data.Bytes(), u.header)
+	obj.etag = fmt.Sprintf(`"%v-%d"`, strings.ToUpper(hex.EncodeToString(sums.Sum(nil))), len(req.Parts))
+	if !s.store(w, r, bucketName, key, obj) {
+		return
+	}
+	s.mu.Lock()
+	delete(s.uploads, uploadId)
+	s.mu.Unlock()
+
+	res := &completeMultipartUploadResult{
+		Location: "/" + bucketName + "/" + key,
+		Bucket:   bucketName,
+		Key:      key,
+		ETag:     obj.etag,
+	}
+	if strings.EqualFold(r.URL.Query().Get("encoding-type"), "url") {
+		res.Key = url.QueryEscape(key)
+		res.EncodingType = "url"
+	}
+	w.Header().Set("x-oss-hash-crc64ecma", strconv.FormatUint(obj.crc64, 10))
+	writeXML(w, http.StatusOK, res)
+}
+
+func (s *Server) abortMultipartUpload(w http.ResponseWriter, r *http.Request, bucketName, key, uploadId string) {
+	if _, ok := s.lookupUpload(w, r, bucketName, key, uploadId); !ok {
+		return
+	}
+	s.mu.Lock()
+	delete(s.uploads, uploadId)
+	s.mu.Unlock()
+	w.WriteHeader(http.StatusNoContent)
+}
@@ -0,0 +1,252 @@
+package osstest
+
+import (
+	"crypto/md5"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"maps"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+)
+
+func etagOf(data []byte) string {
+	sum := md5.Sum(data)
+	return `"` + strings.ToUpper(hex.EncodeToString(sum[:])) + `"`
+}
+
+func (s *Server) serveObject(w http.ResponseWriter, r *http.Request, bucketName, key string) {
+	if s.serveNamespace(w, r, bucketName, key) {
+		return
+	}
+	if s.serveMultipart(w, r, bucketName, key) {
+		return
+	}
+	switch r.Method {
+	case http.MethodPut:
+		if r.Header.Get("x-oss-copy-source") != "" {
+			s.copyObject(w, r, bucketName, key)
+			return
+		}
+		s.putObject(w, r, bucketName, key)
+	case http.MethodGet:
+		s.getObject(w, r, bucketName, key)
+	case http.MethodHead:
+		s.headObject(w, r, bucketName, key)
+	case http.MethodDelete:
+		s.deleteObject(w, r, bucketName, key)
+	default:
+		writeError(w, r, http.StatusNotImplemented, "NotImplemented", "The operation is not supported.")
+	}
+}
+
+// lookup returns the object or writes a NoSuchKey error if it doesn't exist.
+func (s *Server) lookup(w http.ResponseWriter, r *http.Request, bucketName, key string) (*object, bool) {
+	s.mu.Lock()
+	obj, found := s.buckets[bucketName].objects[key]
+	s.mu.Unlock()
+	if !found {
+		writeError(w, r, http.StatusNotFound, "NoSuchKey", "The specified key does not exist.")
+		return nil, false
+	}
+	return obj, true
+}
+
+// store saves obj as key unless overwriting is forbidden by the request.
+func (s *Server) store(w http.ResponseWriter, r *http.Request, bucketName, key string, obj *object) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	objects := s.buckets[bucketName].objects
+	if _, found := objects[key]; found && strings.EqualFold(r.Header.Get("x-oss-forbid-overwrite"), "true") {
+		writeError(w, r, http.StatusConflict, "FileAlreadyExists", "The object you specified already exists and can not be overwritten.")
+		return false
+	}
+	objects[key] = obj
+	return true
+}
+
+func (s *Server) putObject(w http.ResponseWriter, r *http.Request, bucketName, key string) {
+	data, err := io.ReadAll(r.Body)
+	if err != nil {
+		writeError(w, r, http.StatusBadRequest, "IncompleteBody", err.Error())
+		return
+	}
+	obj := newObject(data, r.Header)
+	if !s.store(w, r, bucketName, key, obj) {
+		return
+	}
+	sum := md5.Sum(data)
+	w.Header().Set("ETag", obj.etag)
+	w.Header().Set("Content-MD5", base64.StdEncoding.EncodeToString(sum[:]))
+	w.Header().Set("x-oss-hash-crc64ecma", strconv.FormatUint(obj.crc64, 10))
+	w.WriteHeader(http.StatusOK)
+}
+
+type copyObjectResult struct {
+	XMLName      xml.Name `xml:"CopyObjectResult"`
+	ETag         string   `xml:"ETag"`
+	LastModified string   `xml:"LastModified"`
+}
+
+// parseCopySource parses the x-oss-copy-source header: /bucket/escaped-key.
+func parseCopySource(source string) (string, string, error) {
+	source, _, _ = strings.Cut(source, "?")
+	source, err := url.PathUnescape(strings.TrimPrefix(source, "/"))
+	if err != nil {
+		return "", "", err
+	}
+	bucketName, key, found := strings.Cut(source, "/")
+	if !found || bucketName == "" || key == "" {
+		return "", "", fmt.Errorf("invalid copy source: %v", source)
+	}
+	return bucketName, key, nil
+}
+
+func (s *Server) copyObject(w http.ResponseWriter, r *http.Request, bucketName, key string) {
+	srcBucket, srcKey, err := parseCopySource(r.Header.Get("x-oss-copy-source"))
+	if err != nil {
+		writeError(w, r, http.StatusBadRequest, "InvalidArgument", err.Error())
+		return
+	}
+
+	s.mu.Lock()
+	b, found := s.buckets[srcBucket]
+	var src *object
+	if found {
+		src, found = b.objects[srcKey]
+	}
+	s.mu.Unlock()
+	if !found {
+		writeError(w, r, http.StatusNotFound, "NoSuchKey", "The specified key does not exist.")
+		return
+	}
+
+	obj := newObject(append([]byte(nil), src.data...), r.Header)
+	if !strings.EqualFold(r.Header.Get("x-oss-metadata-directive"), "REPLACE") {
+		obj.contentType = src.contentType
+		obj.metadata = maps.Clone(src.metadata)
+	}
+	if r.Header.Get("x-oss-storage-class") == "" {
+		obj.storageClass = src.storageClass
+	}
+	if !s.store(w, r, bucketName, key, obj) {
+		return
+	}
+	w.Header().Set("x-oss-hash-crc64ecma", strconv.FormatUint(obj.crc64, 10))
+	writeXML(w, http.StatusOK, &copyObjectResult{
+		ETag:         obj.etag,
+		LastModified: obj.lastModified.Format(ossTimeFormat),
+	})
+}
+
+func writeObjectHeaders(w http.ResponseWriter, obj *object) {
+	h := w.Header()
+	h.Set("Content-Type", obj.contentType)
+	h.Set("ETag", obj.etag)
+	h.Set("Last-Modified", obj.lastModified.Format(http.TimeFormat))
+	h.Set("Accept-Ranges", "bytes")
+	h.Set("x-oss-object-type", "Normal")
+	h.Set("x-oss-storage-class", obj.storageClass)
+	h.Set("x-oss-hash-crc64ecma", strconv.FormatUint(obj.crc64, 10))
+	for k, v := range obj.metadata {
+		h.Set(k, v)
+	}
+}
+
+// parseRange parses a single "bytes=start-end" range against size, the
+// returned ok is false if the range cannot be satisfied.
+func parseRange(header string, size int64) (start, end int64, ok bool) {
+	spec, found := strings.CutPrefix(header, "bytes=")
+	if !found || strings.Contains(spec, ",") {
+		return 0, 0, false
+	}
+	first, last, found := strings.Cut(spec, "-")
+	if !found {
+		return 0, 0, false
+	}
+
+	var err error
+	switch {
+	case first == "":
+		// suffix range: the last n bytes.
+		n, e := strconv.ParseInt(last, 10, 64)
+		if e != nil || n <= 0 || size == 0 {
+			return 0, 0, false
+		}
+		return max(size-n, 0), size - 1, true
+	case last == "":
+		end = size - 1
+	default:
+		if end, err = strconv.ParseInt(last, 10, 64); err != nil {
+			return 0, 0, false
+		}
+	}
+	if start, err = strconv.ParseInt(first, 10, 64); err != nil {
+		return 0, 0, false
+	}
+	if start < 0 || start >= size || end < start {
+		return 0, 0, false
+	}
+	return start, min(end, size-1), true
+}
+
+func (s *Server) getObject(w http.ResponseWriter, r *http.Request, bucketName, key string) {
+	obj, ok := s.lookup(w, r, bucketName, key)
+	if !ok {
+		return
+	}
+	size := int64(len(obj.data))
+	writeObjectHeaders(w, obj)
+
+	if rh := r.Header.Get("Range"); rh != "" {
+		start, end, valid := parseRange(rh, size)
+		if valid {
+			w.Header().Set("Content-Range", fmt.Sprintf("bytes %d-%d/%d", start, end, size))
+			w.Header().Set("Content-Length", strconv.FormatInt(end-start+1, 10))
+			w.WriteHeader(http.StatusPartialContent)
+			_, _ = w.Write(obj.data[start : end+1])
+			return
+		}
+		// Only the standard behavior rejects invalid ranges, otherwise the
+		// whole object is returned.
+		if strings.EqualFold(r.Header.Get("x-oss-range-behavior"), "standard") {
+			writeError(w, r, http.StatusRequestedRangeNotSatisfiable, "InvalidRange", "The requested range cannot be satisfied.")
+			return
+		}
+	}
+
+	w.Header().Set("Content-Length", strconv.FormatInt(size, 10))
+	w.WriteHeader(http.StatusOK)
+	_, _ = w.Write(obj.data)
+}
+
+func (s *Server) headObject(w http.ResponseWriter, r *http.Request, bucketName, key string) {
+	if s.headDirectory(w, bucketName, key) {
+		return
+	}
+	obj, ok := s.lookup(w, r, bucketName, key)
+	if !ok {
+		return
+	}
+	if _, meta := r.URL.Query()["objectMeta"]; meta {
+		// GetObjectMeta only returns the basic metadata.
+		w.Header().Set("ETag", obj.etag)
+		w.Header().Set("Last-Modified", obj.lastModified.Format(http.TimeFormat))
+		w.Header().Set("x-oss-hash-crc64ecma", strconv.FormatUint(obj.crc64, 10))
+	} else {
+		writeObjectHeaders(w, obj)
+	}
+	w.Header().Set("Content-Length", strconv.Itoa(len(obj.data)))
+	w.WriteHeader(http.StatusOK)
+}
+
+func (s *Server) deleteObject(w http.ResponseWriter, r *http.Request, bucketName, key string) {
+	s.mu.Lock()
+	delete(s.buckets[bucketName].objects, key)
+	s.mu.Unlock()
+	w.WriteHeader(http.StatusNoContent)
+}
@@ -0,0 +1,189 @@
+/*
+Package osstest provides an OSS compatible HTTP server for tests.
+
+The server keeps objects in memory and speaks enough of the OSS REST API for
+the Alibaba Cloud OSS Go SDK v2 to work against it, which makes it possible to
+exercise ossfs end-to-end without network access:
+
+	srv := osstest.NewServer("test-bucket")
+	defer srv.Close()
+
+	fs := ossfs.NewOssFs("ak", "sk", "cn-hangzhou", "test-bucket",
+		ossfs.OSSWithEndpoint(srv.URL),
+		ossfs.OSSWithUsePathStyle(),
+	)
+
+Requests are neither authenticated nor signature-checked.
+*/
+package osstest
+
+import (
+	"encoding/base64"
+	"encoding/xml"
+	"fmt"
+	"hash/crc64"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+const (
+	ossTimeFormat       = "2006-01-02T15:04:05.000Z"
+	defaultContentType  = "application/octet-stream"
+	defaultStorageClass = "Standard"
+)
+
+var crc64Table = crc64.MakeTable(crc64.ECMA)
+
+// Server is an in-memory OSS emulator listening on a local address, its URL
+// can be used as the endpoint of an OSS client with path style requests.
+type Server struct {
+	*httptest.Server
+
+	mu      sync.Mutex
+	buckets map[string]*bucket
+	uploads map[string]*upload
+	seq     atomic.Uint64
+}
+
+type bucket struct {
+	objects map[string]*object
+
+	// Whether hierarchical namespace is enabled, see
+	// EnableHierarchicalNamespace.
+	hns bool
+}
+
+type object struct {
+	data         []byte
+	etag         string
+	crc64        uint64
+	contentType  string
+	storageClass string
+	metadata     map[string]string
+	lastModified time.Time
+}
+
+// NewServer starts a new Server with the given buckets created, the caller
+// should call Close when finished to shut it down.
+func NewServer(buckets ...string) *Server {
+	s := &Server{
+		buckets: make(map[string]*bucket),
+		uploads: make(map[string]*upload),
+	}
+	for _, b := range buckets {
+		s.CreateBucket(b)
+	}
+	s.Server = httptest.NewServer(http.HandlerFunc(s.serveHTTP))
+	return s
+}
+
+// CreateBucket creates an empty bucket, it does nothing if the bucket exists.
+func (s *Server) CreateBucket(name string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, found := s.buckets[name]; !found {
+		s.buckets[name] = &bucket{objects: make(map[string]*object)}
+	}
+}
+
+func (s *Server) serveHTTP(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Server", "AliyunOSS")
+	w.Header().Set("x-oss-request-id", fmt.Sprintf("%024X", s.seq.Add(1)))
+
+	bucketName, key, _ := strings.Cut(strings.TrimPrefix(r.URL.Path, "/"), "/")
+	if bucketName == "" {
+		writeError(w, r, http.StatusNotImplemented, "NotImplemented", "Service level operations are not supported.")
+		return
+	}
+
+	s.mu.Lock()
+	_, found := s.buckets[bucketName]
+	s.mu.Unlock()
+	if !found {
+		writeError(w, r, http.StatusNotFound, "NoSuchBucket", "The specified bucket does not exist.")
+		return
+	}
+
+	if key == "" {
+		s.serveBucket(w, r, bucketName)
+		return
+	}
+	s.serveObject(w, r, bucketName, key)
+}
+
+// newObject creates an object with its checksums computed from data.
+func newObject(data []byte, header http.Header) *object {
+	obj := &object{
+		data:         data,
+		etag:         etagOf(data),
+		crc64:        crc64.Checksum(data, crc64Table),
+		contentType:  header.Get("Content-Type"),
+		storageClass: header.Get("x-oss-storage-class"),
+		metadata:     userMetadata(header),
+		lastModified: time.Now().UTC().Truncate(time.Second),
+	}
+	if obj.contentType == "" {
+		obj.contentType = defaultContentType
+	}
+	if obj.storageClass == "" {
+		obj.storageClass = defaultStorageClass
+	}
+	return obj
+}
+
+func userMetadata(header http.Header) map[string]string {
+	meta := make(map[string]string)
+	for k, v := range header {
+		lk := strings.ToLower(k)
+		if strings.HasPrefix(lk, "x-oss-meta-") && len(v) > 0 {
+			meta[lk] = v[0]
+		}
+	}
+	return meta
+}
+
+type errorResponse struct {
+	XMLName   xml.Name `xml:"Error"`
+	Code      string   `xml:"Code"`
+	Message   string   `xml:"Message"`
+	RequestId string   `xml:"RequestId"`
+	HostId    string   `xml:"HostId"`
+}
+
+// writeError writes an OSS error response, the error of a HEAD request is
+// carried by the x-oss-err header since it has no body.
+func writeError(w http.ResponseWriter, r *http.Request, status int, code, message string) {
+	body, _ := xml.Marshal(&errorResponse{
+		Code:      code,
+		Message:   message,
+		RequestId: w.Header().Get("x-oss-request-id"),
+		HostId:    r.Host,
+	})
+	body = append([]byte(xml.Header), body...)
+	w.Header().Set("Content-Type", "application/xml")
+	if r.Method == http.MethodHead {
+		w.Header().Set("x-oss-err", base64.StdEncoding.EncodeToString(body))
+		w.WriteHeader(status)
+		return
+	}
+	w.Header().Set("Content-Length", fmt.Sprint(len(body)))
+	w.WriteHeader(status)
+	_, _ = w.Write(body)
+}
+
+func writeXML(w http.ResponseWriter, status int, v any) {
+	body, err := xml.Marshal(v)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	body = append([]byte(xml.Header), body...)
+	w.Header().Set("Content-Type", "application/xml")
+	w.Header().Set("Content-Length", fmt.Sprint(len(body)))
+	w.WriteHeader(status)
+	_, _ = w.Write(body)
+}
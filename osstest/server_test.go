@@ -0,0 +1,274 @@
+package osstest_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/aliyun/alibabacloud-oss-go-sdk-v2/oss"
+	"github.com/aliyun/alibabacloud-oss-go-sdk-v2/oss/credentials"
+	"github.com/stretchr/testify/assert"
+
+	ossfs "github.com/messikiller/afero-oss"
+	"github.com/messikiller/afero-oss/osstest"
+)
+
+func newClientForTest(t *testing.T, buckets ...string) *oss.Client {
+	srv := osstest.NewServer(buckets...)
+	t.Cleanup(srv.Close)
+	cfg := oss.LoadDefaultConfig().
+		WithCredentialsProvider(credentials.NewStaticCredentialsProvider("ak", "sk")).
+		WithRegion("cn-hangzhou").
+		WithEndpoint(srv.URL).
+		WithUsePathStyle(true)
+	return oss.NewClient(cfg)
+}
+
+func putForTest(t *testing.T, c *oss.Client, bucket, key, data string) {
+	_, err := c.PutObject(context.TODO(), &oss.PutObjectRequest{
+		Bucket: oss.Ptr(bucket),
+		Key:    oss.Ptr(key),
+		Body:   strings.NewReader(data),
+	})
+	assert.NoError(t, err)
+}
+
+func TestServerWithOssFs(t *testing.T) {
+	srv := osstest.NewServer("test-bucket")
+	defer srv.Close()
+
+	fs := ossfs.NewOssFs("ak", "sk", "cn-hangzhou", "test-bucket",
+		ossfs.OSSWithEndpoint(srv.URL),
+		ossfs.OSSWithUsePathStyle(),
+	)
+
+	t.Run("write, stat and read a file", func(t *testing.T) {
+		f, err := fs.Create("dir/hello.txt")
+		assert.NoError(t, err)
+		_, err = f.WriteString("hello emulator")
+		assert.NoError(t, err)
+		assert.NoError(t, f.Close())
+
+		fi, err := fs.Stat("dir/hello.txt")
+		assert.NoError(t, err)
+		assert.Equal(t, int64(14), fi.Size())
+
+		f, err = fs.OpenFile("dir/hello.txt", os.O_RDONLY, 0o644)
+		assert.NoError(t, err)
+		p := make([]byte, 8)
+		n, err := f.ReadAt(p, 6)
+		assert.NoError(t, err)
+		assert.Equal(t, 8, n)
+		assert.Equal(t, "emulator", string(p))
+	})
+
+	t.Run("rename and remove", func(t *testing.T) {
+		assert.NoError(t, fs.Rename("dir/hello.txt", "dir/renamed.txt"))
+		_, err := fs.Stat("dir/hello.txt")
+		assert.Error(t, err)
+		_, err = fs.Stat("dir/renamed.txt")
+		assert.NoError(t, err)
+
+		assert.NoError(t, fs.RemoveAll("dir"))
+		_, err = fs.Stat("dir/renamed.txt")
+		assert.Error(t, err)
+	})
+}
+
+func TestServerGetObjectRange(t *testing.T) {
+	c := newClientForTest(t, "bucket")
+	putForTest(t, c, "bucket", "obj", "0123456789")
+
+	get := func(rng string, standard bool) (string, error) {
+		req := &oss.GetObjectRequest{
+			Bucket: oss.Ptr("bucket"),
+			Key:    oss.Ptr("obj"),
+			Range:  oss.Ptr(rng),
+		}
+		if standard {
+			req.RangeBehavior = oss.Ptr("standard")
+		}
+		res, err := c.GetObject(context.TODO(), req)
+		if err != nil {
+			return "", err
+		}
+		defer res.Body.Close()
+		b, err := io.ReadAll(res.Body)
+		return string(b), err
+	}
+
+	tests := []struct {
+		rng      string
+		standard bool
+		expected string
+		code     string
+	}{
+		{rng: "bytes=2-4", expected: "234"},
+		{rng: "bytes=7-", expected: "789"},
+		{rng: "bytes=-3", expected: "789"},
+		{rng: "bytes=8-100", expected: "89"},
+		{rng: "bytes=20-30", expected: "0123456789"},
+		{rng: "bytes=20-30", standard: true, code: "InvalidRange"},
+	}
+	for _, tt := range tests {
+		t.Run(fmt.Sprintf("%v standard=%v", tt.rng, tt.standard), func(t *testing.T) {
+			got, err := get(tt.rng, tt.standard)
+			if tt.code != "" {
+				var serr *oss.ServiceError
+				assert.True(t, errors.As(err, &serr))
+				assert.Equal(t, tt.code, serr.Code)
+				assert.Equal(t, http.StatusRequestedRangeNotSatisfiable, serr.StatusCode)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tt.expected, got)
+		})
+	}
+}
+
+func TestServerHeadAndCopyObject(t *testing.T) {
+	c := newClientForTest(t, "bucket")
+	ctx := context.TODO()
+	_, err := c.PutObject(ctx, &oss.PutObjectRequest{
+		Bucket:       oss.Ptr("bucket"),
+		Key:          oss.Ptr("src.txt"),
+		Body:         strings.NewReader("data"),
+		ContentType:  oss.Ptr("text/plain"),
+		StorageClass: oss.StorageClassIA,
+		Metadata:     map[string]string{"owner": "tester"},
+	})
+	assert.NoError(t, err)
+
+	t.Run("head object returns metadata", func(t *testing.T) {
+		res, err := c.HeadObject(ctx, &oss.HeadObjectRequest{Bucket: oss.Ptr("bucket"), Key: oss.Ptr("src.txt")})
+		assert.NoError(t, err)
+		assert.Equal(t, int64(4), res.ContentLength)
+		assert.Equal(t, "text/plain", oss.ToString(res.ContentType))
+		assert.Equal(t, "IA", oss.ToString(res.StorageClass))
+		assert.Equal(t, "tester", res.Metadata["owner"])
+		assert.NotNil(t, res.LastModified)
+	})
+
+	t.Run("head missing object returns error code", func(t *testing.T) {
+		_, err := c.HeadObject(ctx, &oss.HeadObjectRequest{Bucket: oss.Ptr("bucket"), Key: oss.Ptr("missing")})
+		var serr *oss.ServiceError
+		assert.True(t, errors.As(err, &serr))
+		assert.Equal(t, "NoSuchKey", serr.Code)
+		assert.Equal(t, http.StatusNotFound, serr.StatusCode)
+	})
+
+	t.Run("copy object keeps metadata", func(t *testing.T) {
+		_, err := c.CopyObject(ctx, &oss.CopyObjectRequest{
+			Bucket:    oss.Ptr("bucket"),
+			Key:       oss.Ptr("dst.txt"),
+			SourceKey: oss.Ptr("src.txt"),
+		})
+		assert.NoError(t, err)
+		res, err := c.HeadObject(ctx, &oss.HeadObjectRequest{Bucket: oss.Ptr("bucket"), Key: oss.Ptr("dst.txt")})
+		assert.NoError(t, err)
+		assert.Equal(t, "text/plain", oss.ToString(res.ContentType))
+		assert.Equal(t, "IA", oss.ToString(res.StorageClass))
+		assert.Equal(t, "tester", res.Metadata["owner"])
+	})
+
+	t.Run("forbid overwrite", func(t *testing.T) {
+		_, err := c.PutObject(ctx, &oss.PutObjectRequest{
+			Bucket:          oss.Ptr("bucket"),
+			Key:             oss.Ptr("src.txt"),
+			Body:            strings.NewReader("other"),
+			ForbidOverwrite: oss.Ptr("true"),
+		})
+		var serr *oss.ServiceError
+		assert.True(t, errors.As(err, &serr))
+		assert.Equal(t, "FileAlreadyExists", serr.Code)
+	})
+}
+
+func TestServerListObjectsV2(t *testing.T) {
+	c := newClientForTest(t, "bucket")
+	for _, k := range []string{"a/1", "a/2", "a/b/1", "a/b/2", "a/c/1", "a/d", "a/e f", "b"} {
+		putForTest(t, c, "bucket", k, k)
+	}
+
+	list := func(delimiter string, maxKeys int32) ([]string, []string, int) {
+		req := &oss.ListObjectsV2Request{
+			Bucket:  oss.Ptr("bucket"),
+			Prefix:  oss.Ptr("a/"),
+			MaxKeys: maxKeys,
+		}
+		if delimiter != "" {
+			req.Delimiter = oss.Ptr(delimiter)
+		}
+		p := c.NewListObjectsV2Paginator(req)
+		var keys, prefixes []string
+		pages := 0
+		for p.HasNext() {
+			page, err := p.NextPage(context.TODO())
+			assert.NoError(t, err)
+			pages++
+			for _, obj := range page.Contents {
+				keys = append(keys, oss.ToString(obj.Key))
+			}
+			for _, cp := range page.CommonPrefixes {
+				prefixes = append(prefixes, oss.ToString(cp.Prefix))
+			}
+		}
+		return keys, prefixes, pages
+	}
+
+	t.Run("list recursively", func(t *testing.T) {
+		keys, prefixes, pages := list("", 0)
+		assert.Equal(t, []string{"a/1", "a/2", "a/b/1", "a/b/2", "a/c/1", "a/d", "a/e f"}, keys)
+		assert.Empty(t, prefixes)
+		assert.Equal(t, 1, pages)
+	})
+
+	t.Run("list with delimiter and continuation", func(t *testing.T) {
+		keys, prefixes, pages := list("/", 2)
+		assert.Equal(t, []string{"a/1", "a/2", "a/d", "a/e f"}, keys)
+		assert.Equal(t, []string{"a/b/", "a/c/"}, prefixes)
+		assert.Equal(t, 3, pages)
+	})
+}
+
+func TestServerMultipartUploadWithOssFs(t *testing.T) {
+	srv := osstest.NewServer("test-bucket")
+	defer srv.Close()
+
+	fs := ossfs.NewOssFs("ak", "sk", "cn-hangzhou", "test-bucket",
+		ossfs.OSSWithEndpoint(srv.URL),
+		ossfs.OSSWithUsePathStyle(),
+	).WithMultipartUpload(1024, 3)
+
+	data := strings.Repeat("0123456789", 1000)
+	f, err := fs.OpenFile("large.bin", os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0o644)
+	assert.NoError(t, err)
+	for i := 0; i < len(data); i += 700 {
+		_, err = f.WriteString(data[i:min(i+700, len(data))])
+		assert.NoError(t, err)
+	}
+	assert.NoError(t, f.Sync())
+	assert.Equal(t, 1, srv.Uploads())
+	assert.NoError(t, f.Close())
+	assert.Equal(t, 0, srv.Uploads())
+
+	fi, err := fs.Stat("large.bin")
+	assert.NoError(t, err)
+	assert.Equal(t, int64(len(data)), fi.Size())
+
+	rf, err := fs.OpenFile("large.bin", os.O_RDONLY, 0o644)
+	assert.NoError(t, err)
+	p := make([]byte, 10)
+	_, err = rf.ReadAt(p, 5000)
+	assert.NoError(t, err)
+	assert.Equal(t, "0123456789", string(p))
+
+	b, err := io.ReadAll(rf)
+	assert.NoError(t, err)
+	assert.Equal(t, data, string(b))
+}
@@ -0,0 +1,191 @@
+package ossfs
+
+import (
+	"context"
+	"io"
+	"iter"
+	"os"
+	"path"
+	"strings"
+
+	"github.com/messikiller/afero-oss/internal/utils"
+)
+
+// WithKeyPrefix confines the Fs to the objects under prefix in the bucket,
+// like a chroot. All names, including the names of listed entries and the
+// targets of Rename, are relative to the prefix, and they are cleaned as
+// rooted paths, so "..", e.g. of "../other", cannot escape the prefix.
+func (fs *Fs) WithKeyPrefix(prefix string) *Fs {
+	fs.keyPrefix = prefix
+	fs.applyKeyPrefix()
+	return fs
+}
+
+// applyKeyPrefix wraps the manager by the prefixManager of the key prefix,
+// with the current separator. NewOssFsWithManager applies it again after all
+// options, so the key is the same whatever the order of WithKeyPrefix and
+// WithSeparator is.
+func (fs *Fs) applyKeyPrefix() {
+	base := fs.manager
+	if m, ok := base.(*prefixManager); ok {
+		base = m.ObjectManager
+	}
+	prefix := fs.trimDir(fs.keyPrefix)
+	if prefix == "" {
+		fs.manager = base
+		return
+	}
+	sep := fs.separator
+	if sep == "" {
+		sep = "/"
+	}
+	fs.manager = &prefixManager{ObjectManager: base, prefix: fs.ensureAsDir(prefix), sep: sep}
+}
+
+// Confine the Fs to the objects under prefix, see Fs.WithKeyPrefix.
+func WithKeyPrefix(prefix string) FsOption {
+	return func(fs *Fs) {
+		fs.WithKeyPrefix(prefix)
+	}
+}
+
+// prefixManager is an ObjectManager which maps the names relative to prefix
+// to the keys of objects, and the keys of listed objects back to the names.
+type prefixManager struct {
+	utils.ObjectManager
+	prefix string
+	sep    string
+}
+
+// key returns the key of the relative name, which is cleaned as a rooted path
+// keeping the trailing separator of directories.
+func (m *prefixManager) key(name string) string {
+	if m.sep != "/" {
+		return m.prefix + name
+	}
+	isDir := strings.HasSuffix(name, "/")
+	name = strings.TrimPrefix(path.Clean("/"+name), "/")
+	if name == "" {
+		return m.prefix
+	}
+	if isDir {
+		name += "/"
+	}
+	return m.prefix + name
+}
+
+// entry returns the FileInfo named by the relative name of its key, it
+// returns nil for the directory marker of prefix itself.
+func (m *prefixManager) entry(fi os.FileInfo) os.FileInfo {
+	name := strings.TrimPrefix(fi.Name(), m.prefix)
+	if name == "" {
+		return nil
+	}
+	return &dirEntry{FileInfo: fi, name: name}
+}
+
+func (m *prefixManager) entries(fis []os.FileInfo) []os.FileInfo {
+	out := fis[:0]
+	for _, fi := range fis {
+		if e := m.entry(fi); e != nil {
+			out = append(out, e)
+		}
+	}
+	return out
+}
+
+func (m *prefixManager) GetObject(ctx context.Context, bucket, name string) (io.Reader, utils.CleanUp, error) {
+	return m.ObjectManager.GetObject(ctx, bucket, m.key(name))
+}
+
+func (m *prefixManager) GetObjectPart(ctx context.Context, bucket, name string, start, end int64) (io.Reader, utils.CleanUp, error) {
+	return m.ObjectManager.GetObjectPart(ctx, bucket, m.key(name), start, end)
+}
+
+func (m *prefixManager) DeleteObject(ctx context.Context, bucket, name string) error {
+	return m.ObjectManager.DeleteObject(ctx, bucket, m.key(name))
+}
+
+func (m *prefixManager) DeleteObjects(ctx context.Context, bucket string, names []string) error {
+	keys := make([]string, len(names))
+	for i, name := range names {
+		keys[i] = m.key(name)
+	}
+	return m.ObjectManager.DeleteObjects(ctx, bucket, keys)
+}
+
+func (m *prefixManager) IsObjectExist(ctx context.Context, bucket, name string) (bool, error) {
+	return m.ObjectManager.IsObjectExist(ctx, bucket, m.key(name))
+}
+
+func (m *prefixManager) PutObject(ctx context.Context, bucket, name string, reader io.Reader) (bool, error) {
+	return m.ObjectManager.PutObject(ctx, bucket, m.key(name), reader)
+}
+
+func (m *prefixManager) CopyObject(ctx context.Context, bucket, srcName, targetName string) error {
+	return m.ObjectManager.CopyObject(ctx, bucket, m.key(srcName), m.key(targetName))
+}
+
+func (m *prefixManager) RenameObject(ctx context.Context, bucket, srcName, targetName string) error {
+	return m.ObjectManager.RenameObject(ctx, bucket, m.key(srcName), m.key(targetName))
+}
+
+func (m *prefixManager) CreateDirectory(ctx context.Context, bucket, name string) error {
+	return m.ObjectManager.CreateDirectory(ctx, bucket, m.key(name))
+}
+
+func (m *prefixManager) GetObjectMeta(ctx context.Context, bucket, name string) (os.FileInfo, error) {
+	fi, err := m.ObjectManager.GetObjectMeta(ctx, bucket, m.key(name))
+	if err != nil {
+		return nil, err
+	}
+	if e := m.entry(fi); e != nil {
+		return e, nil
+	}
+	return fi, nil
+}
+
+func (m *prefixManager) ListObjects(ctx context.Context, bucket, prefix string, count int) ([]os.FileInfo, error) {
+	fis, err := m.ObjectManager.ListObjects(ctx, bucket, m.key(prefix), count)
+	return m.entries(fis), err
+}
+
+func (m *prefixManager) ListObjectsPage(ctx context.Context, bucket, prefix, token string, count int) ([]os.FileInfo, string, error) {
+	fis, next, err := m.ObjectManager.ListObjectsPage(ctx, bucket, m.key(prefix), token, count)
+	return m.entries(fis), next, err
+}
+
+func (m *prefixManager) ListAllObjects(ctx context.Context, bucket, prefix string) ([]os.FileInfo, error) {
+	fis, err := m.ObjectManager.ListAllObjects(ctx, bucket, m.key(prefix))
+	return m.entries(fis), err
+}
+
+func (m *prefixManager) IterObjects(ctx context.Context, bucket, prefix string, recursive bool) iter.Seq2[os.FileInfo, error] {
+	return func(yield func(os.FileInfo, error) bool) {
+		for fi, err := range m.ObjectManager.IterObjects(ctx, bucket, m.key(prefix), recursive) {
+			if err != nil {
+				yield(nil, err)
+				return
+			}
+			if e := m.entry(fi); e != nil && !yield(e, nil) {
+				return
+			}
+		}
+	}
+}
+
+func (m *prefixManager) InitiateMultipartUpload(ctx context.Context, bucket, name string) (string, error) {
+	return m.ObjectManager.InitiateMultipartUpload(ctx, bucket, m.key(name))
+}
+
+func (m *prefixManager) UploadPart(ctx context.Context, bucket, name, uploadId string, partNumber int32, reader io.Reader) (utils.UploadedPart, error) {
+	return m.ObjectManager.UploadPart(ctx, bucket, m.key(name), uploadId, partNumber, reader)
+}
+
+func (m *prefixManager) CompleteMultipartUpload(ctx context.Context, bucket, name, uploadId string, parts []utils.UploadedPart) error {
+	return m.ObjectManager.CompleteMultipartUpload(ctx, bucket, m.key(name), uploadId, parts)
+}
+
+func (m *prefixManager) AbortMultipartUpload(ctx context.Context, bucket, name, uploadId string) error {
+	return m.ObjectManager.AbortMultipartUpload(ctx, bucket, m.key(name), uploadId)
+}
@@ -0,0 +1,112 @@
+package ossfs
+
+import (
+	"context"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/spf13/afero"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFsWithKeyPrefix(t *testing.T) {
+	m := NewMemObjectManager()
+	fs := NewOssFsWithManager(m, "test-bucket", WithKeyPrefix("/app/data/"))
+	_, _ = m.PutObject(context.TODO(), "test-bucket", "other.txt", strings.NewReader("other"))
+
+	t.Run("objects are put under the prefix", func(t *testing.T) {
+		assert.NoError(t, afero.WriteFile(fs, "/dir/a.txt", []byte("a"), 0o644))
+		assert.Equal(t, "a", readObjectForTest(t, m, "app/data/dir/a.txt"))
+		b, err := afero.ReadFile(fs, "dir/a.txt")
+		assert.NoError(t, err)
+		assert.Equal(t, "a", string(b))
+	})
+
+	t.Run("names are relative to the prefix", func(t *testing.T) {
+		fi, err := fs.Stat("dir/a.txt")
+		assert.NoError(t, err)
+		assert.Equal(t, "dir/a.txt", fi.Name())
+
+		assert.NoError(t, fs.Mkdir("dir/sub/", 0o755))
+		fis, err := afero.ReadDir(fs, "dir/")
+		assert.NoError(t, err)
+		if assert.Len(t, fis, 2) {
+			assert.Equal(t, "a.txt", fis[0].Name())
+			assert.Equal(t, "sub", fis[1].Name())
+			assert.True(t, fis[1].IsDir())
+		}
+
+		var listed []string
+		for fi, err := range fs.List(context.TODO(), "", true) {
+			assert.NoError(t, err)
+			listed = append(listed, fi.Name())
+		}
+		assert.Equal(t, []string{"dir/a.txt", "dir/sub/"}, listed)
+	})
+
+	t.Run("dot-dot cannot escape the prefix", func(t *testing.T) {
+		_, err := fs.Stat("../../other.txt")
+		assert.ErrorIs(t, err, os.ErrNotExist)
+
+		assert.NoError(t, fs.Rename("dir/a.txt", "../../b.txt"))
+		assert.Equal(t, "a", readObjectForTest(t, m, "app/data/b.txt"))
+		existed, err := m.IsObjectExist(context.TODO(), "test-bucket", "b.txt")
+		assert.NoError(t, err)
+		assert.False(t, existed)
+	})
+
+	t.Run("the root cannot be removed or renamed", func(t *testing.T) {
+		for _, name := range []string{"/", ".", ".."} {
+			assert.ErrorIs(t, fs.RemoveAll(name), os.ErrPermission, name)
+			assert.ErrorIs(t, fs.Rename(name, "moved"), os.ErrInvalid, name)
+			assert.ErrorIs(t, fs.Rename("b.txt", name), os.ErrInvalid, name)
+		}
+		assert.Equal(t, "a", readObjectForTest(t, m, "app/data/b.txt"))
+		assert.Equal(t, "other", readObjectForTest(t, m, "other.txt"))
+	})
+
+	t.Run("remove all only removes the prefix", func(t *testing.T) {
+		assert.NoError(t, fs.RemoveAll("/dir"))
+		fis, err := m.ListAllObjects(context.TODO(), "test-bucket", "app/data/dir/")
+		assert.NoError(t, err)
+		assert.Empty(t, fis)
+		assert.Equal(t, "a", readObjectForTest(t, m, "app/data/b.txt"))
+		assert.Equal(t, "other", readObjectForTest(t, m, "other.txt"))
+	})
+
+	t.Run("replace the prefix", func(t *testing.T) {
+		fs.WithKeyPrefix("logs")
+		assert.Equal(t, "logs/", fs.manager.(*prefixManager).prefix)
+		assert.Same(t, m, fs.manager.(*prefixManager).ObjectManager)
+		fs.WithKeyPrefix("/")
+		assert.Same(t, m, fs.manager)
+	})
+}
+
+func TestFsWithKeyPrefixBlockCache(t *testing.T) {
+	m := &countingManager{MemObjectManager: NewMemObjectManager()}
+	_, _ = m.PutObject(context.TODO(), "test-bucket", "app/hot.txt", strings.NewReader("0123456789"))
+	fs := NewOssFsWithManager(m, "test-bucket", WithKeyPrefix("app")).WithBlockCache(nil, 4, 1024)
+
+	for range 3 {
+		b, err := afero.ReadFile(fs, "hot.txt")
+		assert.NoError(t, err)
+		assert.Equal(t, "0123456789", string(b))
+	}
+	assert.Equal(t, int32(3), m.requests.Load())
+}
+
+func TestFsWithKeyPrefixSeparatorOrder(t *testing.T) {
+	for name, opts := range map[string][]FsOption{
+		"prefix first":    {WithKeyPrefix("p"), WithSeparator(":")},
+		"separator first": {WithSeparator(":"), WithKeyPrefix("p")},
+	} {
+		t.Run(name, func(t *testing.T) {
+			m := NewMemObjectManager()
+			fs := NewOssFsWithManager(m, "test-bucket", opts...)
+			assert.NoError(t, afero.WriteFile(fs, "d:x", []byte("x"), 0o644))
+			assert.Equal(t, "x", readObjectForTest(t, m, "p:d:x"))
+		})
+	}
+}
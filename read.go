@@ -0,0 +1,226 @@
+package ossfs
+
+import (
+	"errors"
+	"io"
+	"sync"
+
+	"github.com/aliyun/alibabacloud-oss-go-sdk-v2/oss"
+
+	"github.com/messikiller/afero-oss/internal/utils"
+)
+
+// WithReadAhead enables prefetching of sequential reads, while a file is read
+// sequentially, up to chunks chunks of chunkSize bytes following the read
+// offset are downloaded in background. Read-ahead is disabled if either of
+// them is not positive.
+func (fs *Fs) WithReadAhead(chunkSize int64, chunks int) *Fs {
+	fs.readAheadSize = chunkSize
+	fs.readAheadChunks = chunks
+	return fs
+}
+
+// WithParallelRead enables parallel ReadAt, a buffer larger than chunkSize is
+// split into chunks of chunkSize bytes which are read by at most workers
+// concurrent range requests. Parallel reads are disabled if chunkSize is not
+// positive.
+func (fs *Fs) WithParallelRead(chunkSize int64, workers int) *Fs {
+	fs.parallelReadSize = chunkSize
+	fs.parallelReadWorkers = max(workers, 1)
+	return fs
+}
+
+// readRange fills p from the object at off by a single range request, it
+// returns io.EOF if the object ends before p is filled.
+func (f *File) readRange(p []byte, off int64) (int, error) {
+	if len(p) == 0 {
+		return 0, nil
+	}
+	fs := f.fs
+	reader, cleanUp, err := fs.manager.GetObjectPart(fs.ctx, fs.bucketName, f.name, off, off+int64(len(p))-1)
+	if err != nil {
+		if isInvalidRange(err) {
+			return 0, io.EOF
+		}
+		return 0, err
+	}
+	defer cleanUp()
+	n, err := io.ReadFull(reader, p)
+	if errors.Is(err, io.ErrUnexpectedEOF) {
+		err = io.EOF
+	}
+	return n, err
+}
+
+// readRangeParallel fills p from the object at off by concurrent range
+// requests of chunkSize bytes, the bytes read are counted up to the first
+// chunk not filled.
+func (f *File) readRangeParallel(p []byte, off int64) (int, error) {
+	chunkSize := int(f.fs.parallelReadSize)
+	count := (len(p) + chunkSize - 1) / chunkSize
+	ns := make([]int, count)
+	errs := make([]error, count)
+
+	sem := make(chan struct{}, max(f.fs.parallelReadWorkers, 1))
+	var wg sync.WaitGroup
+	for i := range count {
+		start := i * chunkSize
+		end := min(start+chunkSize, len(p))
+		sem <- struct{}{}
+		wg.Add(1)
+		go func() {
+			defer func() {
+				<-sem
+				wg.Done()
+			}()
+			ns[i], errs[i] = f.readRange(p[start:end], off+int64(start))
+		}()
+	}
+	wg.Wait()
+
+	n := 0
+	for i := range count {
+		n += ns[i]
+		if errs[i] != nil {
+			return n, errs[i]
+		}
+	}
+	return n, nil
+}
+
+// readStream is the body of a GetObject from offset to the end of the object,
+// sequential reads of a file consume the same body instead of requesting a
+// range for every Read.
+type readStream struct {
+	reader  io.Reader
+	cleanUp utils.CleanUp
+	offset  int64
+	ahead   *readAhead
+}
+
+// openStream opens a readStream of the file from off, it returns io.EOF if off
+// is at or beyond the end of the object.
+func (f *File) openStream(off int64) (*readStream, error) {
+	fs := f.fs
+	reader, cleanUp, err := fs.manager.GetObjectPart(fs.ctx, fs.bucketName, f.name, off, -1)
+	if err != nil {
+		if isInvalidRange(err) {
+			return nil, io.EOF
+		}
+		return nil, err
+	}
+	s := &readStream{
+		reader:  reader,
+		cleanUp: cleanUp,
+		offset:  off,
+	}
+	if fs.readAheadSize > 0 && fs.readAheadChunks > 0 {
+		s.ahead = newReadAhead(reader, fs.readAheadSize, fs.readAheadChunks)
+		s.reader = s.ahead
+	}
+	return s, nil
+}
+
+func (s *readStream) Read(p []byte) (int, error) {
+	n, err := s.reader.Read(p)
+	s.offset += int64(n)
+	return n, err
+}
+
+// Close closes the body, and stops prefetching if any.
+func (s *readStream) Close() {
+	if s.ahead != nil {
+		s.ahead.stop()
+	}
+	s.cleanUp()
+	if s.ahead != nil {
+		s.ahead.wait()
+	}
+}
+
+// closeStream closes the readStream of the file if any, the next Read opens a
+// new one.
+func (f *File) closeStream() {
+	if f.stream != nil {
+		f.stream.Close()
+		f.stream = nil
+	}
+}
+
+// isInvalidRange returns whether err is caused by requesting a range beyond
+// the end of an object.
+func isInvalidRange(err error) bool {
+	var serr *oss.ServiceError
+	return errors.As(err, &serr) && serr.Code == "InvalidRange"
+}
+
+type readChunk struct {
+	data []byte
+	err  error
+}
+
+// readAhead reads chunks of a reader in background, at most cap(chunks)
+// chunks are buffered ahead of the consumer.
+type readAhead struct {
+	chunks chan readChunk
+	done   chan struct{}
+	wg     sync.WaitGroup
+
+	cur []byte
+	err error
+}
+
+func newReadAhead(r io.Reader, chunkSize int64, chunks int) *readAhead {
+	ra := &readAhead{
+		chunks: make(chan readChunk, chunks),
+		done:   make(chan struct{}),
+	}
+	ra.wg.Add(1)
+	go func() {
+		defer ra.wg.Done()
+		defer close(ra.chunks)
+		for {
+			buf := make([]byte, chunkSize)
+			n, err := io.ReadFull(r, buf)
+			if errors.Is(err, io.ErrUnexpectedEOF) {
+				err = io.EOF
+			}
+			select {
+			case ra.chunks <- readChunk{data: buf[:n], err: err}:
+			case <-ra.done:
+				return
+			}
+			if err != nil {
+				return
+			}
+		}
+	}()
+	return ra
+}
+
+func (ra *readAhead) Read(p []byte) (int, error) {
+	for len(ra.cur) == 0 {
+		if ra.err != nil {
+			return 0, ra.err
+		}
+		c, ok := <-ra.chunks
+		if !ok {
+			return 0, io.EOF
+		}
+		ra.cur, ra.err = c.data, c.err
+	}
+	n := copy(p, ra.cur)
+	ra.cur = ra.cur[n:]
+	return n, nil
+}
+
+// stop stops prefetching, the underlying reader should be closed afterwards
+// to unblock a pending read.
+func (ra *readAhead) stop() {
+	close(ra.done)
+}
+
+// wait waits for the prefetching goroutine to exit.
+func (ra *readAhead) wait() {
+	ra.wg.Wait()
+}
@@ -0,0 +1,215 @@
+package ossfs
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"os"
+	"strings"
+	"sync/atomic"
+	"syscall"
+	"testing"
+	"testing/iotest"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/messikiller/afero-oss/internal/mocks"
+	"github.com/messikiller/afero-oss/internal/utils"
+)
+
+// countingManager counts the range requests of objects.
+type countingManager struct {
+	*utils.MemObjectManager
+	requests atomic.Int32
+}
+
+func (m *countingManager) GetObjectPart(ctx context.Context, bucket, name string, start, end int64) (io.Reader, utils.CleanUp, error) {
+	m.requests.Add(1)
+	return m.MemObjectManager.GetObjectPart(ctx, bucket, name, start, end)
+}
+
+func TestFsWithReadAhead(t *testing.T) {
+	fs := &Fs{}
+	got := fs.WithReadAhead(1024, 4)
+	assert.Equal(t, fs, got)
+	assert.Equal(t, int64(1024), got.readAheadSize)
+	assert.Equal(t, 4, got.readAheadChunks)
+}
+
+func TestFsWithParallelRead(t *testing.T) {
+	fs := &Fs{}
+	got := fs.WithParallelRead(1024, 0)
+	assert.Equal(t, fs, got)
+	assert.Equal(t, int64(1024), got.parallelReadSize)
+	assert.Equal(t, 1, got.parallelReadWorkers)
+}
+
+func TestFileReadAtFill(t *testing.T) {
+	t.Run("short reads of body are filled", func(t *testing.T) {
+		fs := getMockedFs(t)
+		f := getMockedFile("testfile", os.O_RDONLY, fs)
+		fs.manager.(*mocks.MockObjectManager).
+			EXPECT().
+			GetObjectPart(fs.ctx, fs.bucketName, "testfile", int64(2), int64(7)).
+			Return(iotest.OneByteReader(strings.NewReader("234567")), func() {}, nil).
+			Once()
+
+		p := make([]byte, 6)
+		n, err := f.ReadAt(p, 2)
+		assert.NoError(t, err)
+		assert.Equal(t, 6, n)
+		assert.Equal(t, "234567", string(p))
+	})
+
+	t.Run("negative offset", func(t *testing.T) {
+		fs := getMockedFs(t)
+		f := getMockedFile("testfile", os.O_RDONLY, fs)
+		_, err := f.ReadAt(make([]byte, 1), -1)
+		assert.ErrorIs(t, err, syscall.EINVAL)
+	})
+
+	t.Run("end of file", func(t *testing.T) {
+		m := NewMemObjectManager()
+		_, _ = m.PutObject(context.TODO(), "test-bucket", "data.txt", strings.NewReader("0123456789"))
+		f := getMockedFile("data.txt", os.O_RDONLY, NewOssFsWithManager(m, "test-bucket"))
+
+		p := make([]byte, 4)
+		n, err := f.ReadAt(p, 8)
+		assert.ErrorIs(t, err, io.EOF)
+		assert.Equal(t, 2, n)
+		assert.Equal(t, "89", string(p[:n]))
+
+		n, err = f.ReadAt(p, 10)
+		assert.ErrorIs(t, err, io.EOF)
+		assert.Equal(t, 0, n)
+	})
+}
+
+func TestFileReadAtParallel(t *testing.T) {
+	data := strings.Repeat("0123456789", 10)
+	m := &countingManager{MemObjectManager: NewMemObjectManager()}
+	_, _ = m.PutObject(context.TODO(), "test-bucket", "data.txt", strings.NewReader(data))
+	fs := NewOssFsWithManager(m, "test-bucket").WithParallelRead(8, 3)
+	f := getMockedFile("data.txt", os.O_RDONLY, fs)
+
+	tests := []struct {
+		name     string
+		size     int
+		off      int64
+		n        int
+		err      error
+		requests int32
+	}{
+		{name: "small buffer in single request", size: 8, off: 0, n: 8, requests: 1},
+		{name: "large buffer split into chunks", size: 50, off: 5, n: 50, requests: 7},
+		{name: "chunks beyond end of file", size: 40, off: 75, n: 25, err: io.EOF, requests: 5},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m.requests.Store(0)
+			p := make([]byte, tt.size)
+			n, err := f.ReadAt(p, tt.off)
+			assert.Equal(t, tt.err, err)
+			assert.Equal(t, tt.n, n)
+			assert.Equal(t, data[tt.off:tt.off+int64(n)], string(p[:n]))
+			assert.Equal(t, tt.requests, m.requests.Load())
+		})
+	}
+}
+
+func TestFileReadStream(t *testing.T) {
+	data := strings.Repeat("0123456789", 100)
+
+	tests := []struct {
+		name   string
+		chunk  int64
+		chunks int
+	}{
+		{name: "without read-ahead"},
+		{name: "with read-ahead", chunk: 64, chunks: 3},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m := &countingManager{MemObjectManager: NewMemObjectManager()}
+			_, _ = m.PutObject(context.TODO(), "test-bucket", "data.txt", strings.NewReader(data))
+			fs := NewOssFsWithManager(m, "test-bucket").WithReadAhead(tt.chunk, tt.chunks)
+
+			f, err := fs.OpenFile("data.txt", os.O_RDONLY, 0o644)
+			assert.NoError(t, err)
+
+			// small reads share one request.
+			var buf bytes.Buffer
+			_, err = io.CopyBuffer(&buf, struct{ io.Reader }{f}, make([]byte, 7))
+			assert.NoError(t, err)
+			assert.Equal(t, data, buf.String())
+			assert.Equal(t, int32(1), m.requests.Load())
+
+			// reading at the end returns io.EOF.
+			n, err := f.Read(make([]byte, 10))
+			assert.Equal(t, 0, n)
+			assert.ErrorIs(t, err, io.EOF)
+
+			// seeking reopens the stream.
+			off, err := f.Seek(995, io.SeekStart)
+			assert.NoError(t, err)
+			assert.Equal(t, int64(995), off)
+			b, err := io.ReadAll(f)
+			assert.NoError(t, err)
+			assert.Equal(t, "56789", string(b))
+
+			assert.NoError(t, f.Close())
+			assert.Nil(t, f.(*File).stream)
+		})
+	}
+}
+
+func TestFileReadStreamInvalidatedByWrite(t *testing.T) {
+	m := NewMemObjectManager()
+	_, _ = m.PutObject(context.TODO(), "test-bucket", "data.txt", strings.NewReader("0123456789"))
+	fs := NewOssFsWithManager(m, "test-bucket")
+
+	f, err := fs.OpenFile("data.txt", os.O_RDWR, 0o644)
+	assert.NoError(t, err)
+	p := make([]byte, 2)
+	_, err = f.Read(p)
+	assert.NoError(t, err)
+	assert.Equal(t, "01", string(p))
+
+	_, err = f.WriteAt([]byte("ab"), 2)
+	assert.NoError(t, err)
+	assert.Nil(t, f.(*File).stream)
+
+	_, err = f.Read(p)
+	assert.NoError(t, err)
+	assert.Equal(t, "ab", string(p))
+}
+
+func TestFileReadAheadStop(t *testing.T) {
+	m := mocks.NewMockObjectManager(t)
+	fs := &Fs{manager: m, bucketName: "test-bucket", ctx: context.TODO(), separator: "/"}
+	fs.WithReadAhead(4, 2)
+
+	// the body never ends until it's closed.
+	pr, pw := io.Pipe()
+	go func() {
+		_, _ = pw.Write([]byte("0123456789"))
+	}()
+	var cleaned atomic.Bool
+	m.EXPECT().GetObjectPart(fs.ctx, "test-bucket", "data.txt", int64(0), int64(-1)).
+		Return(pr, func() {
+			cleaned.Store(true)
+			_ = pr.Close()
+		}, nil).Once()
+
+	f := getMockedFile("data.txt", os.O_RDONLY, fs)
+	p := make([]byte, 4)
+	_, err := io.ReadFull(f, p)
+	assert.NoError(t, err)
+	assert.Equal(t, "0123", string(p))
+
+	f.closeStream()
+	assert.True(t, cleaned.Load())
+	assert.Nil(t, f.stream)
+}
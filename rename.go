@@ -0,0 +1,83 @@
+package ossfs
+
+import (
+	"errors"
+	"strings"
+	"sync"
+	"syscall"
+
+	"github.com/spf13/afero"
+)
+
+// renameConcurrency is the number of objects copied at the same time when a
+// directory is renamed.
+const renameConcurrency = 8
+
+// renameFile moves the object oldname to newname.
+func (fs *Fs) renameFile(oldname, newname string) error {
+	defer fs.invalidateCache(oldname)
+	defer fs.invalidateCache(newname)
+	err := fs.manager.CopyObject(fs.ctx, fs.bucketName, oldname, newname)
+	if err != nil {
+		return err
+	}
+	err = fs.manager.DeleteObject(fs.ctx, fs.bucketName, oldname)
+	return err
+}
+
+// renameDir moves all objects under the directory oldDir to newDir, including
+// the directory marker. The objects are copied concurrently, and they are
+// deleted only if all of them are copied, so a failed rename leaves oldDir
+// intact.
+func (fs *Fs) renameDir(oldDir, newDir string) error {
+	if strings.HasPrefix(newDir, oldDir) {
+		return syscall.EINVAL
+	}
+
+	var (
+		wg    sync.WaitGroup
+		mu    sync.Mutex
+		errs  []error
+		names []string
+	)
+	sem := make(chan struct{}, renameConcurrency)
+	for fi, err := range fs.manager.IterObjects(fs.ctx, fs.bucketName, oldDir, true) {
+		if err != nil {
+			mu.Lock()
+			errs = append(errs, err)
+			mu.Unlock()
+			break
+		}
+		oldname := fi.Name()
+		newname := newDir + strings.TrimPrefix(oldname, oldDir)
+		names = append(names, oldname)
+
+		sem <- struct{}{}
+		wg.Add(1)
+		go func() {
+			defer func() {
+				<-sem
+				wg.Done()
+			}()
+			defer fs.invalidateCache(newname)
+			if err := fs.manager.CopyObject(fs.ctx, fs.bucketName, oldname, newname); err != nil {
+				mu.Lock()
+				errs = append(errs, err)
+				mu.Unlock()
+			}
+		}()
+	}
+	wg.Wait()
+
+	if len(errs) > 0 {
+		return errors.Join(errs...)
+	}
+	if len(names) == 0 {
+		return afero.ErrFileNotFound
+	}
+	err := fs.manager.DeleteObjects(fs.ctx, fs.bucketName, names)
+	for _, name := range names {
+		fs.invalidateCache(name)
+	}
+	return err
+}
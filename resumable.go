@@ -0,0 +1,211 @@
+package ossfs
+
+import (
+	"crypto/md5"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"hash/crc64"
+	"io"
+	"os"
+	"path/filepath"
+
+	"github.com/aliyun/alibabacloud-oss-go-sdk-v2/oss"
+
+	"github.com/messikiller/afero-oss/internal/utils"
+)
+
+var crc64Table = crc64.MakeTable(crc64.ECMA)
+
+// uploadCheckpoint records the progress of a resumable upload, it's saved as
+// a JSON file in the checkpoint directory after every uploaded part.
+type uploadCheckpoint struct {
+	Bucket   string           `json:"bucket"`
+	Key      string           `json:"key"`
+	Size     int64            `json:"size"`
+	PartSize int64            `json:"part_size"`
+	UploadId string           `json:"upload_id"`
+	Parts    []checkpointPart `json:"parts"`
+}
+
+// checkpointPart is an uploaded part, the CRC64 of its data is used to check
+// whether the local data is still the same when resuming.
+type checkpointPart struct {
+	PartNumber int32  `json:"part_number"`
+	ETag       string `json:"etag"`
+	CRC64      uint64 `json:"crc64"`
+}
+
+// part returns the uploaded part of the part number.
+func (cp *uploadCheckpoint) part(partNumber int32) (checkpointPart, bool) {
+	for _, p := range cp.Parts {
+		if p.PartNumber == partNumber {
+			return p, true
+		}
+	}
+	return checkpointPart{}, false
+}
+
+// setPart records p as uploaded, replacing the part of the same number.
+func (cp *uploadCheckpoint) setPart(p checkpointPart) {
+	for i := range cp.Parts {
+		if cp.Parts[i].PartNumber == p.PartNumber {
+			cp.Parts[i] = p
+			return
+		}
+	}
+	cp.Parts = append(cp.Parts, p)
+}
+
+// WithResumableUpload enables resumable uploads when syncing a preloaded file
+// larger than partSize. The file is uploaded by a multipart upload whose
+// progress is saved into a checkpoint file under checkpointDir, so if the
+// upload is interrupted, syncing the same data again, even from another
+// process, only uploads the parts not uploaded yet. The checkpoint is removed
+// once the upload is completed.
+//
+// OSS requires the part size to be at least 100 KiB. An empty checkpointDir
+// disables resumable uploads.
+func (fs *Fs) WithResumableUpload(checkpointDir string, partSize int64) *Fs {
+	fs.checkpointDir = checkpointDir
+	fs.resumablePartSize = partSize
+	return fs
+}
+
+// isResumable returns whether an object of size bytes should be uploaded by
+// resumableUpload.
+func (fs *Fs) isResumable(size int64) bool {
+	return fs.checkpointDir != "" && fs.resumablePartSize > 0 && size > fs.resumablePartSize
+}
+
+// checkpointPath returns the path of the checkpoint file of an object, it's
+// named by the key of the object, so the Fs confined to different prefixes of
+// the bucket never share a checkpoint.
+func (fs *Fs) checkpointPath(name string) string {
+	if m, ok := fs.manager.(*prefixManager); ok {
+		name = m.key(name)
+	}
+	sum := md5.Sum([]byte(fs.bucketName + "/" + name))
+	return filepath.Join(fs.checkpointDir, fmt.Sprintf("%x.cp", sum))
+}
+
+// loadCheckpoint loads the checkpoint of an object, it returns nil if there is
+// no checkpoint or it was saved for an upload of different size or part size.
+func (fs *Fs) loadCheckpoint(name string, size int64) *uploadCheckpoint {
+	b, err := os.ReadFile(fs.checkpointPath(name))
+	if err != nil {
+		return nil
+	}
+	cp := &uploadCheckpoint{}
+	if err := json.Unmarshal(b, cp); err != nil {
+		return nil
+	}
+	if cp.Bucket != fs.bucketName || cp.Key != name || cp.Size != size ||
+		cp.PartSize != fs.resumablePartSize || cp.UploadId == "" {
+		return nil
+	}
+	return cp
+}
+
+// saveCheckpoint saves cp by renaming a temporary file, so a crash never leaves
+// a partially written checkpoint.
+func (fs *Fs) saveCheckpoint(cp *uploadCheckpoint) error {
+	b, err := json.Marshal(cp)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(fs.checkpointDir, 0o755); err != nil {
+		return err
+	}
+	path := fs.checkpointPath(cp.Key)
+	if err := os.WriteFile(path+".tmp", b, 0o644); err != nil {
+		return err
+	}
+	return os.Rename(path+".tmp", path)
+}
+
+// isNoSuchUpload returns whether err is caused by an upload which doesn't
+// exist any more, e.g. it's expired or aborted.
+func isNoSuchUpload(err error) bool {
+	var serr *oss.ServiceError
+	return errors.As(err, &serr) && serr.Code == "NoSuchUpload"
+}
+
+// resumableUpload uploads size bytes of r as the object name, resuming the
+// upload from its checkpoint if any. A new upload is started if the checkpoint
+// refers to an upload which doesn't exist any more.
+func (fs *Fs) resumableUpload(name string, r io.ReaderAt, size int64) error {
+	err := fs.doResumableUpload(name, r, size)
+	if isNoSuchUpload(err) {
+		if e := os.Remove(fs.checkpointPath(name)); e != nil && !errors.Is(e, os.ErrNotExist) {
+			return e
+		}
+		err = fs.doResumableUpload(name, r, size)
+	}
+	return err
+}
+
+func (fs *Fs) doResumableUpload(name string, r io.ReaderAt, size int64) error {
+	if (size+fs.resumablePartSize-1)/fs.resumablePartSize > maxPartNumber {
+		return errors.New("OSS: too many parts of multipart upload, increase the part size")
+	}
+	cp := fs.loadCheckpoint(name, size)
+	if cp == nil {
+		uploadId, err := fs.manager.InitiateMultipartUpload(fs.ctx, fs.bucketName, name)
+		if err != nil {
+			return err
+		}
+		cp = &uploadCheckpoint{
+			Bucket:   fs.bucketName,
+			Key:      name,
+			Size:     size,
+			PartSize: fs.resumablePartSize,
+			UploadId: uploadId,
+		}
+		if err := fs.saveCheckpoint(cp); err != nil {
+			return err
+		}
+	}
+
+	var parts []utils.UploadedPart
+	partNumber := int32(1)
+	for off := int64(0); off < size; off += cp.PartSize {
+		n := min(cp.PartSize, size-off)
+		crc, err := checksumSection(io.NewSectionReader(r, off, n))
+		if err != nil {
+			return err
+		}
+
+		// The part is uploaded again if the local data was changed.
+		p, found := cp.part(partNumber)
+		if !found || p.CRC64 != crc {
+			part, err := fs.manager.UploadPart(fs.ctx, fs.bucketName, name, cp.UploadId, partNumber, io.NewSectionReader(r, off, n))
+			if err != nil {
+				return err
+			}
+			p = checkpointPart{PartNumber: partNumber, ETag: part.ETag, CRC64: crc}
+			cp.setPart(p)
+			if err := fs.saveCheckpoint(cp); err != nil {
+				return err
+			}
+		}
+		parts = append(parts, utils.UploadedPart{PartNumber: p.PartNumber, ETag: p.ETag})
+		partNumber++
+	}
+
+	if err := fs.manager.CompleteMultipartUpload(fs.ctx, fs.bucketName, name, cp.UploadId, parts); err != nil {
+		return err
+	}
+	if err := os.Remove(fs.checkpointPath(name)); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+	return nil
+}
+
+func checksumSection(r io.Reader) (uint64, error) {
+	h := crc64.New(crc64Table)
+	if _, err := io.Copy(h, r); err != nil {
+		return 0, err
+	}
+	return h.Sum64(), nil
+}
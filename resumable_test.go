@@ -0,0 +1,157 @@
+package ossfs
+
+import (
+	"context"
+	"errors"
+	"io"
+	"os"
+	"strings"
+	"sync/atomic"
+	"testing"
+
+	"github.com/spf13/afero"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/messikiller/afero-oss/internal/utils"
+)
+
+// interruptedManager fails uploading parts after failAfter parts are uploaded,
+// to simulate an interrupted upload.
+type interruptedManager struct {
+	*utils.MemObjectManager
+	failAfter int32
+	uploaded  atomic.Int32
+}
+
+var errInterrupted = errors.New("upload interrupted")
+
+func (m *interruptedManager) UploadPart(ctx context.Context, bucket, name, uploadId string, partNumber int32, reader io.Reader) (utils.UploadedPart, error) {
+	if m.failAfter >= 0 && m.uploaded.Load() >= m.failAfter {
+		return utils.UploadedPart{}, errInterrupted
+	}
+	part, err := m.MemObjectManager.UploadPart(ctx, bucket, name, uploadId, partNumber, reader)
+	if err == nil {
+		m.uploaded.Add(1)
+	}
+	return part, err
+}
+
+func TestFsWithResumableUpload(t *testing.T) {
+	fs := &Fs{}
+	got := fs.WithResumableUpload("/tmp/checkpoints", 1024)
+	assert.Equal(t, fs, got)
+	assert.Equal(t, "/tmp/checkpoints", got.checkpointDir)
+	assert.Equal(t, int64(1024), got.resumablePartSize)
+
+	assert.False(t, fs.isResumable(1024))
+	assert.True(t, fs.isResumable(1025))
+	assert.False(t, fs.WithResumableUpload("", 1024).isResumable(1025))
+}
+
+func TestFileSyncResumableUpload(t *testing.T) {
+	mem := NewMemObjectManager()
+	m := &interruptedManager{MemObjectManager: mem, failAfter: 2}
+	dir := t.TempDir()
+	data := "0123456789abcdefghij"
+	fs := NewOssFsWithManager(m, "test-bucket").WithResumableUpload(dir, 4)
+
+	// every write starts with a new Fs as if the process was restarted.
+	write := func(s string) error {
+		fs := NewOssFsWithManager(m, "test-bucket").WithResumableUpload(dir, 4)
+		fs.syncPolicy = SyncPolicy{Mode: SyncOnClose}
+		f, err := fs.OpenFile("large.txt", os.O_RDWR|os.O_CREATE|os.O_TRUNC, 0o644)
+		if !assert.NoError(t, err) {
+			return err
+		}
+		_, err = f.WriteString(s)
+		assert.NoError(t, err)
+		return f.Close()
+	}
+
+	t.Run("interrupted upload keeps checkpoint", func(t *testing.T) {
+		assert.ErrorIs(t, write(data), errInterrupted)
+
+		cp := fs.loadCheckpoint("large.txt", int64(len(data)))
+		assert.NotNil(t, cp)
+		assert.Len(t, cp.Parts, 2)
+		assert.Equal(t, 1, mem.MultipartUploads())
+	})
+
+	t.Run("resume from checkpoint", func(t *testing.T) {
+		m.failAfter = -1
+		m.uploaded.Store(0)
+		assert.NoError(t, write(data))
+
+		// only the 3 parts not uploaded before are uploaded.
+		assert.Equal(t, int32(3), m.uploaded.Load())
+		assert.Equal(t, 0, mem.MultipartUploads())
+		assert.Equal(t, data, readObjectForTest(t, mem, "large.txt"))
+		_, err := os.Stat(fs.checkpointPath("large.txt"))
+		assert.ErrorIs(t, err, os.ErrNotExist)
+	})
+
+	t.Run("changed parts are uploaded again", func(t *testing.T) {
+		m.failAfter = 2
+		m.uploaded.Store(0)
+		assert.ErrorIs(t, write(data), errInterrupted)
+
+		m.failAfter = -1
+		m.uploaded.Store(0)
+		changed := "0123XXXX89abcdefghij"
+		assert.NoError(t, write(changed))
+		assert.Equal(t, int32(4), m.uploaded.Load())
+		assert.Equal(t, changed, readObjectForTest(t, mem, "large.txt"))
+	})
+
+	t.Run("restart if the upload does not exist", func(t *testing.T) {
+		m.failAfter = 1
+		m.uploaded.Store(0)
+		assert.ErrorIs(t, write(data), errInterrupted)
+
+		cp := fs.loadCheckpoint("large.txt", int64(len(data)))
+		assert.NotNil(t, cp)
+		assert.NoError(t, mem.AbortMultipartUpload(context.TODO(), "test-bucket", "large.txt", cp.UploadId))
+
+		m.failAfter = -1
+		m.uploaded.Store(0)
+		assert.NoError(t, write(data))
+		assert.Equal(t, int32(5), m.uploaded.Load())
+		assert.Equal(t, data, readObjectForTest(t, mem, "large.txt"))
+	})
+
+	t.Run("small file is put at once", func(t *testing.T) {
+		assert.NoError(t, write("abc"))
+		assert.Equal(t, "abc", readObjectForTest(t, mem, "large.txt"))
+		entries, err := os.ReadDir(dir)
+		assert.NoError(t, err)
+		assert.Empty(t, entries)
+	})
+
+	t.Run("corrupted checkpoint is ignored", func(t *testing.T) {
+		assert.NoError(t, os.WriteFile(fs.checkpointPath("large.txt"), []byte(strings.Repeat("{", 3)), 0o644))
+		assert.Nil(t, fs.loadCheckpoint("large.txt", int64(len(data))))
+		assert.NoError(t, write(data))
+		assert.Equal(t, data, readObjectForTest(t, mem, "large.txt"))
+	})
+}
+
+func TestFileSyncResumableUploadWithKeyPrefix(t *testing.T) {
+	mem := NewMemObjectManager()
+	m := &interruptedManager{MemObjectManager: mem, failAfter: 2}
+	dir := t.TempDir()
+	data := "0123456789abcdefghij"
+	fsA := NewOssFsWithManager(m, "test-bucket", WithKeyPrefix("a")).WithResumableUpload(dir, 4)
+	fsB := NewOssFsWithManager(m, "test-bucket", WithKeyPrefix("b")).WithResumableUpload(dir, 4)
+	assert.NotEqual(t, fsA.checkpointPath("large.txt"), fsB.checkpointPath("large.txt"))
+
+	assert.ErrorIs(t, afero.WriteFile(fsA, "large.txt", []byte(data), 0o644), errInterrupted)
+	assert.NotNil(t, fsA.loadCheckpoint("large.txt", int64(len(data))))
+	assert.Nil(t, fsB.loadCheckpoint("large.txt", int64(len(data))))
+
+	m.failAfter = -1
+	m.uploaded.Store(0)
+	assert.NoError(t, afero.WriteFile(fsB, "large.txt", []byte(data), 0o644))
+	assert.Equal(t, int32(5), m.uploaded.Load())
+	assert.Equal(t, data, readObjectForTest(t, mem, "b/large.txt"))
+	assert.NotNil(t, fsA.loadCheckpoint("large.txt", int64(len(data))))
+}
@@ -0,0 +1,164 @@
+package ossfs
+
+import (
+	"io"
+	"sync"
+	"time"
+
+	"github.com/spf13/afero"
+)
+
+// sharedFile is the state shared by the handles written on the same file,
+// i.e. the preloaded copy they write and sync, so a handle doesn't discard the
+// writes of another one by preloading the file again.
+type sharedFile struct {
+	name string
+	refs int // guarded by Fs.sharedMu
+
+	mu          sync.Mutex
+	preloadedFd afero.File
+	dirty       bool
+	pending     bool // whether there are writes not synced yet
+
+	// The bytes written since the last upload, and the timer of the idle
+	// write-back upload, see SyncWriteBack.
+	dirtyBytes int64
+	timer      *time.Timer
+}
+
+// acquireShared returns the shared state of the file name, a reference to it
+// is held until releaseShared.
+func (fs *Fs) acquireShared(name string) *sharedFile {
+	fs.sharedMu.Lock()
+	defer fs.sharedMu.Unlock()
+	if fs.sharedFiles == nil {
+		fs.sharedFiles = make(map[string]*sharedFile)
+	}
+	s, found := fs.sharedFiles[name]
+	if !found {
+		s = &sharedFile{name: name}
+		fs.sharedFiles[name] = s
+	}
+	s.refs++
+	return s
+}
+
+// releaseShared drops a reference to the shared state, the preloaded copy is
+// removed once the last reference is dropped.
+func (fs *Fs) releaseShared(s *sharedFile) error {
+	// The preloaded copy is removed with the lock held, so a handle acquiring
+	// the file again doesn't preload it before that.
+	fs.sharedMu.Lock()
+	defer fs.sharedMu.Unlock()
+	s.refs--
+	if s.refs > 0 {
+		return nil
+	}
+	delete(fs.sharedFiles, s.name)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.timer != nil {
+		s.timer.Stop()
+	}
+	if s.preloadedFd == nil {
+		return nil
+	}
+	if err := fs.preloadFs.Remove(s.name); err != nil {
+		return err
+	}
+	err := s.preloadedFd.Close()
+	s.preloadedFd = nil
+	s.dirty = false
+	s.pending = false
+	return err
+}
+
+// truncateShared truncates the preloaded copy shared by the open handles of
+// the file name, if any, after the object was truncated by OpenFile, so the
+// handles don't upload its former content again.
+func (fs *Fs) truncateShared(name string) error {
+	fs.sharedMu.Lock()
+	s, found := fs.sharedFiles[name]
+	fs.sharedMu.Unlock()
+	if !found {
+		return nil
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.preloadedFd == nil {
+		return nil
+	}
+	if err := s.preloadedFd.Truncate(0); err != nil {
+		return err
+	}
+	// The copy is the same as the truncated object now.
+	s.pending = false
+	s.dirtyBytes = 0
+	return nil
+}
+
+// preload preloads the file to the preload file system if it's not yet, the
+// caller must hold s.mu.
+func (s *sharedFile) preload(fs *Fs) error {
+	if s.preloadedFd != nil {
+		return nil
+	}
+	pfs := fs.preloadFs
+	if _, err := pfs.Stat(s.name); err == nil {
+		if e := pfs.Remove(s.name); e != nil {
+			return e
+		}
+	}
+	pfd, err := pfs.Create(s.name)
+	if err != nil {
+		return err
+	}
+
+	r, clean, e := fs.manager.GetObject(fs.ctx, fs.bucketName, s.name)
+	if e != nil {
+		return e
+	}
+	defer clean()
+
+	if _, err := io.Copy(pfd, r); err != nil {
+		return err
+	}
+
+	s.preloadedFd = pfd
+	return nil
+}
+
+// syncShared uploads the preloaded copy of the file, a copy larger than the
+// part size of resumable uploads is uploaded from its checkpoint.
+func (fs *Fs) syncShared(s *sharedFile) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.preloadedFd == nil || !s.pending {
+		return nil
+	}
+	fi, err := s.preloadedFd.Stat()
+	if err != nil {
+		return err
+	}
+	// The cache is dropped after the upload, so a concurrent Stat doesn't
+	// cache the former metadata again.
+	defer fs.invalidateCache(s.name)
+	if fs.isResumable(fi.Size()) {
+		if err := fs.resumableUpload(s.name, s.preloadedFd, fi.Size()); err != nil {
+			return err
+		}
+		s.pending = false
+		s.dirtyBytes = 0
+		return nil
+	}
+	off, _ := s.preloadedFd.Seek(0, io.SeekCurrent)
+	s.preloadedFd.Seek(0, io.SeekStart)
+	if _, err := fs.manager.PutObject(fs.ctx, fs.bucketName, s.name, s.preloadedFd); err != nil {
+		return err
+	}
+	s.preloadedFd.Seek(off, io.SeekStart)
+	s.pending = false
+	s.dirtyBytes = 0
+	return nil
+}
@@ -0,0 +1,132 @@
+package ossfs
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/spf13/afero"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFsIndependentHandles(t *testing.T) {
+	newFs := func(content string) *Fs {
+		fs := NewOssFsWithManager(NewMemObjectManager(), "test-bucket")
+		f, err := fs.Create("a.txt")
+		assert.NoError(t, err)
+		_, err = f.WriteString(content)
+		assert.NoError(t, err)
+		assert.NoError(t, f.Close())
+		return fs
+	}
+	readAll := func(fs *Fs) string {
+		b, err := afero.ReadFile(fs, "a.txt")
+		assert.NoError(t, err)
+		return string(b)
+	}
+
+	t.Run("handles have their own offsets", func(t *testing.T) {
+		fs := newFs("hello world")
+		f1, err := fs.Open("a.txt")
+		assert.NoError(t, err)
+		f2, err := fs.Open("a.txt")
+		assert.NoError(t, err)
+
+		p := make([]byte, 5)
+		_, err = io.ReadFull(f1, p)
+		assert.NoError(t, err)
+		assert.Equal(t, "hello", string(p))
+		_, err = io.ReadFull(f2, p)
+		assert.NoError(t, err)
+		assert.Equal(t, "hello", string(p))
+
+		assert.NoError(t, f1.Close())
+		_, err = io.ReadFull(f2, p)
+		assert.NoError(t, err)
+		assert.Equal(t, " worl", string(p))
+		assert.NoError(t, f2.Close())
+	})
+
+	t.Run("handles share the preloaded copy", func(t *testing.T) {
+		fs := newFs("")
+		fs.syncPolicy = SyncPolicy{Mode: SyncOnClose}
+		f1, err := fs.OpenFile("a.txt", os.O_RDWR, 0)
+		assert.NoError(t, err)
+		f2, err := fs.OpenFile("a.txt", os.O_RDWR, 0)
+		assert.NoError(t, err)
+
+		_, err = f1.WriteAt([]byte("aaa"), 0)
+		assert.NoError(t, err)
+		_, err = f2.WriteAt([]byte("bbb"), 3)
+		assert.NoError(t, err)
+		fi, err := f1.Stat()
+		assert.NoError(t, err)
+		assert.Equal(t, int64(6), fi.Size())
+
+		assert.NoError(t, f1.Close())
+		assert.Equal(t, "aaabbb", readAll(fs))
+		_, err = f2.WriteAt([]byte("c"), 6)
+		assert.NoError(t, err)
+		assert.NoError(t, f2.Close())
+		assert.Equal(t, "aaabbbc", readAll(fs))
+
+		existed, err := afero.Exists(fs.preloadFs, "a.txt")
+		assert.NoError(t, err)
+		assert.False(t, existed)
+		assert.Empty(t, fs.sharedFiles)
+	})
+
+	t.Run("concurrent handles", func(t *testing.T) {
+		fs := newFs("")
+		var wg sync.WaitGroup
+		for i := range 8 {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				f, err := fs.OpenFile("a.txt", os.O_RDWR, 0)
+				if !assert.NoError(t, err) {
+					return
+				}
+				_, err = f.WriteAt([]byte(fmt.Sprint(i)), int64(i))
+				assert.NoError(t, err)
+				assert.NoError(t, f.Close())
+			}()
+		}
+		wg.Wait()
+		assert.Equal(t, "01234567", readAll(fs))
+		assert.Empty(t, fs.sharedFiles)
+	})
+
+	t.Run("close twice", func(t *testing.T) {
+		fs := newFs("data")
+		f, err := fs.OpenFile("a.txt", os.O_RDWR, 0)
+		assert.NoError(t, err)
+		_, err = f.WriteString(strings.ToUpper("d"))
+		assert.NoError(t, err)
+		assert.NoError(t, f.Close())
+		assert.NoError(t, f.Close())
+		assert.Equal(t, "Data", readAll(fs))
+	})
+
+	t.Run("truncate discards the preloaded copy of other handles", func(t *testing.T) {
+		for _, policy := range []SyncPolicy{{}, {Mode: SyncOnClose}} {
+			fs := newFs("")
+			fs.syncPolicy = policy
+			f1, err := fs.OpenFile("a.txt", os.O_RDWR|os.O_CREATE, 0)
+			assert.NoError(t, err)
+			_, err = f1.WriteString("hello")
+			assert.NoError(t, err)
+
+			f2, err := fs.OpenFile("a.txt", os.O_RDWR|os.O_TRUNC, 0)
+			assert.NoError(t, err)
+			_, err = f2.WriteString("x")
+			assert.NoError(t, err)
+			assert.NoError(t, f2.Close())
+			assert.NoError(t, f1.Close())
+			assert.Equal(t, "x", readAll(fs))
+		}
+	})
+}
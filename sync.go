@@ -0,0 +1,117 @@
+package ossfs
+
+import (
+	"io"
+	"time"
+)
+
+// SyncMode is when the writes of preloaded files are uploaded, see
+// SyncPolicy.
+type SyncMode int
+
+const (
+	// SyncImmediate uploads the preloaded file on every write, it's the
+	// default.
+	SyncImmediate SyncMode = iota
+	// SyncOnClose uploads the preloaded file only by File.Sync, File.Close,
+	// Fs.Flush and Fs.Close.
+	SyncOnClose
+	// SyncWriteBack uploads the preloaded file in background after the
+	// writes are idle for a delay, or enough bytes are written, see
+	// SyncPolicy.
+	SyncWriteBack
+)
+
+// SyncPolicy decides when the writes of preloaded files are uploaded, see
+// Fs.WithSyncPolicy.
+type SyncPolicy struct {
+	Mode SyncMode
+
+	// Delay is the idle time after the last write before a write-back
+	// upload, zero disables it.
+	Delay time.Duration
+
+	// DirtyBytes starts a write-back upload once this many bytes are written
+	// since the last upload, zero disables it.
+	DirtyBytes int64
+}
+
+// WithSyncPolicy sets when the writes of preloaded files are uploaded. By
+// default every write uploads the whole file, SyncOnClose and SyncWriteBack
+// save the uploads of many small writes. File.Sync still uploads the pending
+// writes at once.
+//
+// A failed write-back upload is logged, see WithLogger, and retried by the
+// next upload, at the latest by File.Close, which reports the error. Reads of
+// the file see the pending writes from the preloaded copy.
+func (fs *Fs) WithSyncPolicy(policy SyncPolicy) *Fs {
+	fs.syncPolicy = policy
+	return fs
+}
+
+// afterWrite applies the sync policy after n bytes are written to the
+// preloaded copy of the file, the caller must hold f.mu.
+func (f *File) afterWrite(n int) {
+	policy := f.fs.syncPolicy
+	switch policy.Mode {
+	case SyncImmediate:
+		f.fs.logError("ossfs: sync failed", f.name, f.syncLocked())
+	case SyncWriteBack:
+		f.fs.scheduleWriteBack(f.shared, int64(n))
+	}
+}
+
+// scheduleWriteBack counts the written bytes of the file, and starts the
+// write-back upload by the policy.
+func (fs *Fs) scheduleWriteBack(s *sharedFile, n int64) {
+	policy := fs.syncPolicy
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.dirtyBytes += n
+	if policy.DirtyBytes > 0 && s.dirtyBytes >= policy.DirtyBytes {
+		s.dirtyBytes = 0
+		go fs.writeBack(s)
+		return
+	}
+	if policy.Delay <= 0 {
+		return
+	}
+	if s.timer == nil {
+		s.timer = time.AfterFunc(policy.Delay, func() {
+			fs.writeBack(s)
+		})
+		return
+	}
+	s.timer.Reset(policy.Delay)
+}
+
+// writeBack uploads the preloaded copy in background, a failed upload is
+// logged and left pending.
+func (fs *Fs) writeBack(s *sharedFile) {
+	fs.logError("ossfs: write-back failed", s.name, fs.syncShared(s))
+}
+
+// readPreloaded reads from the preloaded copy of the file at off if it has
+// writes not uploaded yet, ok is false if it has not.
+func (f *File) readPreloaded(p []byte, off int64) (n int, ok bool, err error) {
+	s := f.shared
+	if s == nil {
+		f.fs.sharedMu.Lock()
+		s = f.fs.sharedFiles[f.name]
+		f.fs.sharedMu.Unlock()
+	}
+	if s == nil {
+		return 0, false, nil
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if !s.pending || s.preloadedFd == nil {
+		return 0, false, nil
+	}
+	n, err = s.preloadedFd.ReadAt(p, off)
+	if err == nil && n < len(p) {
+		err = io.EOF
+	}
+	return n, true, err
+}
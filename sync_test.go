@@ -0,0 +1,127 @@
+package ossfs
+
+import (
+	"io"
+	"os"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFsSyncPolicy(t *testing.T) {
+	openForTest := func(t *testing.T, policy SyncPolicy) (*File, *Fs, *failingPutManager) {
+		fs, m := newFlushTestFs(t)
+		fs.WithSyncPolicy(policy)
+		f, err := fs.OpenFile("a.txt", os.O_RDWR, 0)
+		assert.NoError(t, err)
+		return f.(*File), fs, m
+	}
+	writeForTest := func(t *testing.T, f *File, s string) {
+		_, err := f.WriteString(s)
+		assert.NoError(t, err)
+	}
+
+	t.Run("immediate uploads every write", func(t *testing.T) {
+		f, fs, m := openForTest(t, SyncPolicy{})
+		writeForTest(t, f, "a")
+		writeForTest(t, f, "b")
+		assert.Equal(t, int32(2), m.puts.Load())
+		assert.Equal(t, "ab", readObjectForTest(t, fs.manager, "a.txt"))
+		assert.NoError(t, f.Close())
+		assert.Equal(t, int32(2), m.puts.Load())
+	})
+
+	t.Run("on close uploads once", func(t *testing.T) {
+		f, fs, m := openForTest(t, SyncPolicy{Mode: SyncOnClose})
+		for _, s := range []string{"a", "b", "c"} {
+			writeForTest(t, f, s)
+		}
+		assert.Equal(t, int32(0), m.puts.Load())
+		assert.Equal(t, "", readObjectForTest(t, fs.manager, "a.txt"))
+
+		assert.NoError(t, f.Close())
+		assert.Equal(t, int32(1), m.puts.Load())
+		assert.Equal(t, "abc", readObjectForTest(t, fs.manager, "a.txt"))
+	})
+
+	t.Run("reads see pending writes", func(t *testing.T) {
+		f, fs, _ := openForTest(t, SyncPolicy{Mode: SyncOnClose})
+		writeForTest(t, f, "hello")
+		_, err := f.Seek(0, io.SeekStart)
+		assert.NoError(t, err)
+		b, err := io.ReadAll(f)
+		assert.NoError(t, err)
+		assert.Equal(t, "hello", string(b))
+
+		other, err := fs.Open("a.txt")
+		assert.NoError(t, err)
+		p := make([]byte, 3)
+		n, err := other.ReadAt(p, 2)
+		assert.NoError(t, err)
+		assert.Equal(t, "llo", string(p[:n]))
+		assert.NoError(t, other.Close())
+		assert.NoError(t, f.Close())
+	})
+
+	t.Run("sync forces upload", func(t *testing.T) {
+		f, fs, m := openForTest(t, SyncPolicy{Mode: SyncOnClose})
+		writeForTest(t, f, "data")
+		assert.NoError(t, f.Sync())
+		assert.Equal(t, int32(1), m.puts.Load())
+		assert.Equal(t, "data", readObjectForTest(t, fs.manager, "a.txt"))
+
+		// Nothing is pending for Close.
+		assert.NoError(t, f.Close())
+		assert.Equal(t, int32(1), m.puts.Load())
+	})
+
+	t.Run("write back after idle delay", func(t *testing.T) {
+		f, fs, m := openForTest(t, SyncPolicy{Mode: SyncWriteBack, Delay: 20 * time.Millisecond})
+		writeForTest(t, f, "a")
+		writeForTest(t, f, "b")
+		assert.Equal(t, int32(0), m.puts.Load())
+		assert.Eventually(t, func() bool { return m.puts.Load() == 1 }, time.Second, 5*time.Millisecond)
+		assert.Equal(t, "ab", readObjectForTest(t, fs.manager, "a.txt"))
+		assert.NoError(t, f.Close())
+		assert.Equal(t, int32(1), m.puts.Load())
+	})
+
+	t.Run("write back after dirty bytes", func(t *testing.T) {
+		f, fs, m := openForTest(t, SyncPolicy{Mode: SyncWriteBack, DirtyBytes: 4})
+		writeForTest(t, f, "ab")
+		assert.Equal(t, int32(0), m.puts.Load())
+		writeForTest(t, f, "cd")
+		assert.Eventually(t, func() bool { return m.puts.Load() == 1 }, time.Second, 5*time.Millisecond)
+		assert.Equal(t, "abcd", readObjectForTest(t, fs.manager, "a.txt"))
+		assert.NoError(t, f.Close())
+	})
+
+	t.Run("failed write back is retried by close", func(t *testing.T) {
+		f, fs, m := openForTest(t, SyncPolicy{Mode: SyncWriteBack, DirtyBytes: 1})
+		m.fail.Store(true)
+		writeForTest(t, f, "data")
+		assert.Eventually(t, func() bool { return m.puts.Load() == 1 }, time.Second, 5*time.Millisecond)
+
+		m.fail.Store(false)
+		assert.NoError(t, f.Close())
+		assert.Equal(t, "data", readObjectForTest(t, fs.manager, "a.txt"))
+	})
+	t.Run("sync races neither first write nor close", func(t *testing.T) {
+		f, fs, _ := openForTest(t, SyncPolicy{Mode: SyncOnClose})
+		var wg sync.WaitGroup
+		wg.Add(2)
+		go func() {
+			defer wg.Done()
+			_ = f.Sync()
+		}()
+		go func() {
+			defer wg.Done()
+			writeForTest(t, f, "data")
+			assert.NoError(t, f.Close())
+		}()
+		wg.Wait()
+		assert.Equal(t, "data", readObjectForTest(t, fs.manager, "a.txt"))
+	})
+}
@@ -0,0 +1,162 @@
+package ossfs
+
+import (
+	"bytes"
+	"errors"
+	"slices"
+	"sync"
+
+	"github.com/messikiller/afero-oss/internal/utils"
+)
+
+const maxPartNumber = 10000 // the maximum number of parts of a multipart upload.
+
+// multipartWriter streams sequential writes of a file into a multipart upload,
+// every partSize bytes written become a part which is uploaded in background
+// by at most concurrency goroutines. The upload is initiated lazily, so a file
+// smaller than a part is uploaded by a single PutObject on Close.
+type multipartWriter struct {
+	fs         *Fs
+	name       string
+	partSize   int64
+	buf        []byte
+	size       int64
+	uploadId   string
+	partNumber int32
+	sem        chan struct{}
+	wg         sync.WaitGroup
+
+	mu    sync.Mutex // guards parts and err, which are set by uploading goroutines.
+	parts []utils.UploadedPart
+	err   error
+}
+
+// newMultipartWriter creates a multipartWriter of the file with the multipart
+// upload settings of fs.
+func newMultipartWriter(fs *Fs, name string) *multipartWriter {
+	return &multipartWriter{
+		fs:       fs,
+		name:     name,
+		partSize: fs.multipartPartSize,
+		buf:      make([]byte, 0, fs.multipartPartSize),
+		sem:      make(chan struct{}, max(fs.multipartConcurrency, 1)),
+	}
+}
+
+// Write buffers p and uploads every filled part, it returns the error of any
+// failed part so the caller stops writing as soon as possible.
+func (w *multipartWriter) Write(p []byte) (int, error) {
+	if err := w.error(); err != nil {
+		return 0, err
+	}
+	n := 0
+	for len(p) > 0 {
+		k := min(len(p), int(w.partSize)-len(w.buf))
+		w.buf = append(w.buf, p[:k]...)
+		p = p[k:]
+		n += k
+		w.size += int64(k)
+		if int64(len(w.buf)) == w.partSize {
+			if err := w.uploadPart(); err != nil {
+				return n, err
+			}
+		}
+	}
+	return n, nil
+}
+
+// uploadPart uploads the buffered data as the next part in background.
+func (w *multipartWriter) uploadPart() error {
+	fs := w.fs
+	if w.partNumber == maxPartNumber {
+		return w.setError(errors.New("OSS: too many parts of multipart upload, increase the part size"))
+	}
+	if w.uploadId == "" {
+		uploadId, err := fs.manager.InitiateMultipartUpload(fs.ctx, fs.bucketName, w.name)
+		if err != nil {
+			return w.setError(err)
+		}
+		w.uploadId = uploadId
+	}
+
+	w.partNumber++
+	partNumber, data := w.partNumber, w.buf
+	w.buf = make([]byte, 0, w.partSize)
+
+	w.sem <- struct{}{}
+	w.wg.Add(1)
+	go func() {
+		defer func() {
+			<-w.sem
+			w.wg.Done()
+		}()
+		part, err := fs.manager.UploadPart(fs.ctx, fs.bucketName, w.name, w.uploadId, partNumber, bytes.NewReader(data))
+		if err != nil {
+			w.setError(err)
+			return
+		}
+		w.mu.Lock()
+		w.parts = append(w.parts, part)
+		w.mu.Unlock()
+	}()
+	return nil
+}
+
+// setError records err if no error happened before, it returns the recorded
+// error.
+func (w *multipartWriter) setError(err error) error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.err == nil {
+		w.err = err
+	}
+	return w.err
+}
+
+func (w *multipartWriter) error() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.err
+}
+
+// Sync waits for the uploading parts, the buffered data is kept until the
+// part is filled or the writer is closed.
+func (w *multipartWriter) Sync() error {
+	w.wg.Wait()
+	return w.error()
+}
+
+// Close uploads the buffered data and completes the multipart upload, the
+// upload is aborted if any part failed or it cannot be completed.
+func (w *multipartWriter) Close() error {
+	fs := w.fs
+	if w.uploadId == "" {
+		if err := w.error(); err != nil || w.size == 0 {
+			// The object was created empty when the file was opened.
+			return err
+		}
+		_, err := fs.manager.PutObject(fs.ctx, fs.bucketName, w.name, bytes.NewReader(w.buf))
+		return err
+	}
+
+	if len(w.buf) > 0 {
+		_ = w.uploadPart()
+	}
+	w.wg.Wait()
+
+	err := w.error()
+	if err == nil {
+		parts := slices.Clone(w.parts)
+		slices.SortFunc(parts, func(a, b utils.UploadedPart) int {
+			return int(a.PartNumber - b.PartNumber)
+		})
+		err = fs.manager.CompleteMultipartUpload(fs.ctx, fs.bucketName, w.name, w.uploadId, parts)
+	}
+	if err != nil {
+		if e := fs.manager.AbortMultipartUpload(fs.ctx, fs.bucketName, w.name, w.uploadId); e != nil {
+			return errors.Join(err, e)
+		}
+		return err
+	}
+	return nil
+}
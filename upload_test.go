@@ -0,0 +1,206 @@
+package ossfs
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"os"
+	"strings"
+	"syscall"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+
+	"github.com/messikiller/afero-oss/internal/mocks"
+	"github.com/messikiller/afero-oss/internal/utils"
+)
+
+func TestFsWithMultipartUpload(t *testing.T) {
+	tests := []struct {
+		name        string
+		partSize    int64
+		concurrency int
+		expected    *Fs
+	}{
+		{
+			name:        "set part size and concurrency",
+			partSize:    8 << 20,
+			concurrency: 4,
+			expected:    &Fs{multipartPartSize: 8 << 20, multipartConcurrency: 4},
+		},
+		{
+			name:        "concurrency at least one",
+			partSize:    8 << 20,
+			concurrency: 0,
+			expected:    &Fs{multipartPartSize: 8 << 20, multipartConcurrency: 1},
+		},
+		{
+			name:        "disable with zero part size",
+			partSize:    0,
+			concurrency: 4,
+			expected:    &Fs{multipartPartSize: 0, multipartConcurrency: 4},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fs := &Fs{}
+			got := fs.WithMultipartUpload(tt.partSize, tt.concurrency)
+			assert.Equal(t, fs, got)
+			assert.Equal(t, tt.expected.multipartPartSize, got.multipartPartSize)
+			assert.Equal(t, tt.expected.multipartConcurrency, got.multipartConcurrency)
+		})
+	}
+}
+
+func readObjectForTest(t *testing.T, m ObjectManager, name string) string {
+	r, clean, err := m.GetObject(context.TODO(), "test-bucket", name)
+	if !assert.NoError(t, err) {
+		return ""
+	}
+	defer clean()
+	b, err := io.ReadAll(r)
+	assert.NoError(t, err)
+	return string(b)
+}
+
+func TestFileMultipartUpload(t *testing.T) {
+	m := NewMemObjectManager()
+	fs := NewOssFsWithManager(m, "test-bucket").WithMultipartUpload(4, 2)
+	flag := os.O_WRONLY | os.O_CREATE | os.O_TRUNC
+
+	t.Run("stream writes into parts", func(t *testing.T) {
+		f, err := fs.OpenFile("large.txt", flag, 0o644)
+		assert.NoError(t, err)
+		assert.NotNil(t, f.(*File).writer)
+
+		for _, s := range []string{"0123", "456", "789ab", "c"} {
+			_, err = f.WriteString(s)
+			assert.NoError(t, err)
+		}
+		assert.NoError(t, f.Sync())
+		assert.Equal(t, 1, m.MultipartUploads())
+
+		fi, err := f.Stat()
+		assert.NoError(t, err)
+		assert.Equal(t, int64(13), fi.Size())
+
+		assert.NoError(t, f.Close())
+		assert.Equal(t, 0, m.MultipartUploads())
+		assert.Equal(t, "0123456789abc", readObjectForTest(t, m, "large.txt"))
+	})
+
+	t.Run("small file is put at once", func(t *testing.T) {
+		f, err := fs.OpenFile("small.txt", flag, 0o644)
+		assert.NoError(t, err)
+		_, err = f.WriteString("abc")
+		assert.NoError(t, err)
+		assert.Equal(t, 0, m.MultipartUploads())
+		assert.NoError(t, f.Close())
+		assert.Equal(t, "abc", readObjectForTest(t, m, "small.txt"))
+	})
+
+	t.Run("only sequential writes are allowed", func(t *testing.T) {
+		f, err := fs.OpenFile("seq.txt", flag, 0o644)
+		assert.NoError(t, err)
+		_, err = f.WriteString("01234")
+		assert.NoError(t, err)
+
+		_, err = f.WriteAt([]byte("x"), 0)
+		assert.ErrorIs(t, err, syscall.ESPIPE)
+		_, err = f.Seek(0, io.SeekStart)
+		assert.ErrorIs(t, err, syscall.ESPIPE)
+		assert.ErrorIs(t, f.Truncate(0), syscall.EPERM)
+
+		off, err := f.Seek(0, io.SeekCurrent)
+		assert.NoError(t, err)
+		assert.Equal(t, int64(5), off)
+		_, err = f.WriteAt([]byte("56"), 5)
+		assert.NoError(t, err)
+
+		assert.NoError(t, f.Close())
+		assert.Equal(t, "0123456", readObjectForTest(t, m, "seq.txt"))
+	})
+
+	t.Run("not streamed unless write-only from empty", func(t *testing.T) {
+		_, _ = m.PutObject(context.TODO(), "test-bucket", "existed.txt", strings.NewReader("data"))
+		for _, flag := range []int{
+			os.O_RDWR | os.O_CREATE | os.O_TRUNC,
+			os.O_WRONLY | os.O_APPEND,
+			os.O_WRONLY,
+		} {
+			f, err := fs.OpenFile("existed.txt", flag, 0o644)
+			assert.NoError(t, err)
+			assert.Nil(t, f.(*File).writer)
+			assert.NoError(t, f.Close())
+		}
+	})
+}
+
+func TestFileMultipartUploadAbort(t *testing.T) {
+	m := mocks.NewMockObjectManager(t)
+	bucket := "test-bucket"
+	ctx := context.TODO()
+	fs := &Fs{
+		manager:    m,
+		bucketName: bucket,
+		ctx:        ctx,
+		separator:  "/",
+	}
+	fs.WithMultipartUpload(4, 1)
+
+	t.Run("abort when a part failed", func(t *testing.T) {
+		uploadErr := errors.New("upload part failed")
+		f := getMockedFile("test.txt", os.O_WRONLY, fs)
+		f.writer = newMultipartWriter(fs, f.name)
+		m.EXPECT().InitiateMultipartUpload(ctx, bucket, "test.txt").Return("upload-id", nil).Once()
+		m.EXPECT().UploadPart(ctx, bucket, "test.txt", "upload-id", int32(1), mock.Anything).
+			Return(utils.UploadedPart{}, uploadErr).Once()
+		m.EXPECT().AbortMultipartUpload(ctx, bucket, "test.txt", "upload-id").Return(nil).Once()
+
+		_, err := f.WriteString("0123")
+		assert.NoError(t, err)
+		assert.ErrorIs(t, f.Sync(), uploadErr)
+		_, err = f.WriteString("4567")
+		assert.ErrorIs(t, err, uploadErr)
+		assert.ErrorIs(t, f.Close(), uploadErr)
+		assert.True(t, f.closed)
+		m.AssertExpectations(t)
+	})
+
+	t.Run("abort when completion failed", func(t *testing.T) {
+		completeErr := errors.New("complete failed")
+		f := getMockedFile("test.txt", os.O_WRONLY, fs)
+		f.writer = newMultipartWriter(fs, f.name)
+		m.EXPECT().InitiateMultipartUpload(ctx, bucket, "test.txt").Return("upload-id", nil).Once()
+		m.EXPECT().UploadPart(ctx, bucket, "test.txt", "upload-id", int32(1), bytes.NewReader([]byte("0123"))).
+			Return(utils.UploadedPart{PartNumber: 1, ETag: "etag-1"}, nil).Once()
+		m.EXPECT().UploadPart(ctx, bucket, "test.txt", "upload-id", int32(2), bytes.NewReader([]byte("45"))).
+			Return(utils.UploadedPart{PartNumber: 2, ETag: "etag-2"}, nil).Once()
+		m.EXPECT().CompleteMultipartUpload(ctx, bucket, "test.txt", "upload-id", []utils.UploadedPart{
+			{PartNumber: 1, ETag: "etag-1"},
+			{PartNumber: 2, ETag: "etag-2"},
+		}).Return(completeErr).Once()
+		m.EXPECT().AbortMultipartUpload(ctx, bucket, "test.txt", "upload-id").Return(nil).Once()
+
+		_, err := f.WriteString("012345")
+		assert.NoError(t, err)
+		assert.ErrorIs(t, f.Close(), completeErr)
+		m.AssertExpectations(t)
+	})
+
+	t.Run("initiate failed", func(t *testing.T) {
+		initErr := errors.New("initiate failed")
+		f := getMockedFile("test.txt", os.O_WRONLY, fs)
+		f.writer = newMultipartWriter(fs, f.name)
+		m.EXPECT().InitiateMultipartUpload(ctx, bucket, "test.txt").Return("", initErr).Once()
+
+		n, err := f.WriteString("012345")
+		assert.ErrorIs(t, err, initErr)
+		assert.Equal(t, 4, n)
+		assert.ErrorIs(t, f.Close(), initErr)
+		m.AssertExpectations(t)
+	})
+}